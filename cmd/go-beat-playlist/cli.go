@@ -0,0 +1,505 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cosandr/go-beat-playlist/download"
+	"github.com/cosandr/go-beat-playlist/library"
+	"github.com/cosandr/go-beat-playlist/playlist"
+	"github.com/cosandr/go-beat-playlist/sources"
+)
+
+// command is a non-interactive subcommand, an alternative to the main menu
+type command struct {
+	name string
+	args string
+	help string
+	run  func(c *command, lib *library.Library, args []string) error
+}
+
+// commands lists all subcommands in the order they are shown in the usage
+var commands = []command{
+	{
+		name: "playlist",
+		args: "list | show TITLE...",
+		help: "Show all read playlists or the songs of the given playlists",
+		run:  cmdPlaylist,
+	},
+	{
+		name: "songs",
+		args: "[-folder NAME] [-env NAME] [-sort bpm|njs|name] [-json]",
+		help: "Show all installed song data",
+		run:  cmdSongs,
+	},
+	{
+		name: "orphans",
+		args: "[-add FILE [-merge]] [-delete | -move]",
+		help: "Songs not in any playlists",
+		run:  cmdOrphans,
+	},
+	{
+		name: "missing",
+		args: "[-prune [-backup]]",
+		help: "Songs missing from playlists",
+		run:  cmdMissing,
+	},
+	{
+		name: "download",
+		args: "[-parallel N] [-limit KBPS] [-delay DURATION] [TITLE...]",
+		help: "Download songs missing from all or the given playlists",
+		run:  cmdDownload,
+	},
+	{
+		name: "queue",
+		args: "list | retry [ID...] | cancel [-all] [ID...]",
+		help: "Show the download queue, or retry or cancel failed songs by key or hash, all failed songs if none given",
+		run:  cmdQueue,
+	},
+	{
+		name: "top-stars",
+		args: "[-o FILE [-backup]] [-diff max|min|DIFFICULTY] [-each] N",
+		help: "Create playlist of N songs sorted by ScoreSaber star difficulty",
+		run:  cmdTopStars,
+	},
+	{
+		name: "top-pp",
+		args: "[-o FILE [-backup]] [-diff max|min|DIFFICULTY] [-each] N",
+		help: "Create playlist of N songs sorted by PP using Song Browser data",
+		run:  cmdTopPP,
+	},
+	{
+		name: "verify",
+		args: "[-delete | -move]",
+		help: "Check local song hashes",
+		run:  cmdVerify,
+	},
+	{
+		name: "watch",
+		args: "[-interval DURATION]",
+		help: "Watch songs and playlists, printing changes until interrupted",
+		run:  cmdWatch,
+	},
+}
+
+// usage prints the global flags and all subcommands
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage: %s [flags] [command [command flags] [args]]\n\n", os.Args[0])
+	fmt.Fprintln(out, "Starts the interactive menu when no command is given.\n\nFlags:")
+	flag.PrintDefaults()
+	fmt.Fprintln(out, "\nCommands:")
+	for _, c := range commands {
+		fmt.Fprintf(out, "  %s %s\n    \t%s\n", c.name, c.args, c.help)
+	}
+}
+
+// runCommand runs the subcommand named by the first element of `args`
+func runCommand(lib *library.Library, args []string) error {
+	for i := range commands {
+		if commands[i].name == args[0] {
+			err := commands[i].run(&commands[i], lib, args[1:])
+			if err == flag.ErrHelp {
+				return nil
+			}
+			return err
+		}
+	}
+	return fmt.Errorf("unknown command %q, run with -h for a list of commands", args[0])
+}
+
+// flagSet returns a new FlagSet for this command with its usage text
+func (c *command) flagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(c.name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s %s\n\n%s\n", c.name, c.args, c.help)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// songAction adds the mutually exclusive -delete and -move flags to `fs`
+//
+// The returned function reports if any was set and whether songs should be moved
+func songAction(fs *flag.FlagSet, what string) func() (act bool, move bool, err error) {
+	del := fs.Bool("delete", false, "Delete "+what)
+	move := fs.Bool("move", false, "Move "+what+" to DeletedSongs")
+	return func() (bool, bool, error) {
+		if *del && *move {
+			return false, false, fmt.Errorf("-delete and -move are mutually exclusive")
+		}
+		return *del || *move, *move, nil
+	}
+}
+
+// findPlaylists returns the playlists matching each of `names` by title, path or file name
+func findPlaylists(lib *library.Library, names []string) ([]playlist.Playlist, error) {
+	var ret []playlist.Playlist
+	for _, name := range names {
+		found := lib.FindPlaylists(name)
+		if len(found) == 0 {
+			return nil, fmt.Errorf("playlist %q not found", name)
+		}
+		ret = append(ret, found...)
+	}
+	return ret, nil
+}
+
+func cmdPlaylist(c *command, lib *library.Library, args []string) error {
+	fs := c.flagSet()
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	args = fs.Args()
+	if len(args) == 0 {
+		return fmt.Errorf("missing playlist subcommand, expected list or show")
+	}
+	switch args[0] {
+	case "list":
+		for _, p := range lib.Playlists() {
+			fmt.Printf("%s (%d songs) %s\n", p.Title, len(p.Songs), p.File)
+		}
+	case "show":
+		if len(args) == 1 {
+			printAllPlaylists(lib)
+			return nil
+		}
+		playlists, err := findPlaylists(lib, args[1:])
+		if err != nil {
+			return err
+		}
+		for _, p := range playlists {
+			fmt.Println(p.String())
+		}
+	default:
+		return fmt.Errorf("unknown playlist subcommand %q, expected list or show", args[0])
+	}
+	return nil
+}
+
+func cmdSongs(c *command, lib *library.Library, args []string) error {
+	fs := c.flagSet()
+	folder := fs.String("folder", "", "Only show songs in this song folder, such as \""+library.CustomWIPLevels+"\"")
+	env := fs.String("env", "", "Only show songs using this environment")
+	sortBy := fs.String("sort", "", "Sort songs by bpm, njs (highest of all difficulties) or name")
+	asJSON := fs.Bool("json", false, "Print all song data as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	songs := lib.Songs()
+	if *folder != "" {
+		songs = songs.Filter(func(s *playlist.Song) bool { return s.Folder == *folder })
+		songs.Title = *folder
+	}
+	if *env != "" {
+		songs = songs.Filter(func(s *playlist.Song) bool { return strings.EqualFold(s.Environment, *env) })
+	}
+	switch *sortBy {
+	case "":
+	case "bpm":
+		songs.SortByBPM()
+	case "njs":
+		songs.SortByNJS()
+	case "name":
+		sort.SliceStable(songs.Songs, func(i, j int) bool {
+			return strings.ToLower(songs.Songs[i].Name) < strings.ToLower(songs.Songs[j].Name)
+		})
+	default:
+		return fmt.Errorf("cannot sort by %q", *sortBy)
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", " ")
+		return enc.Encode(songs.Songs)
+	}
+	fmt.Println(songs.String())
+	return nil
+}
+
+func cmdOrphans(c *command, lib *library.Library, args []string) error {
+	fs := c.flagSet()
+	add := fs.String("add", "", "Add orphans to this playlist file in the playlists folder")
+	merge := fs.Bool("merge", false, "Merge with the playlist given by -add if it exists, instead of overwriting it")
+	action := songAction(fs, "all orphans")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	act, move, err := action()
+	if err != nil {
+		return err
+	}
+	conf := lib.Config()
+	orphansPlaylist := lib.Orphans()
+	if *add == "" && !act {
+		fmt.Print(orphansPlaylist.String())
+		return nil
+	}
+	if *add != "" {
+		if err := addToPlaylist(conf.Playlists+"/"+*add, orphansPlaylist, *merge); err != nil {
+			return err
+		}
+		fmt.Printf("Added %d songs to %s\n", len(orphansPlaylist.Songs), *add)
+	}
+	if act {
+		deleteSongsFromPlaylist(&conf, orphansPlaylist, move)
+	}
+	return nil
+}
+
+func cmdMissing(c *command, lib *library.Library, args []string) error {
+	fs := c.flagSet()
+	prune := fs.Bool("prune", false, "Remove missing songs from playlists")
+	backup := fs.Bool("backup", false, "Backup playlists before pruning them")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	missingPlaylists := lib.Missing()
+	if !*prune {
+		fmt.Printf("## %d songs missing from all playlists ##\n\n", countMissing(missingPlaylists))
+		for _, p := range missingPlaylists {
+			fmt.Println(p.String())
+		}
+		return nil
+	}
+	return pruneMissing(lib, missingPlaylists, *backup)
+}
+
+func cmdDownload(c *command, lib *library.Library, args []string) error {
+	fs := c.flagSet()
+	df := defaultDownloadFlags
+	fs.IntVar(&df.parallel, "parallel", df.parallel, "Number of songs downloaded at the same time")
+	fs.Int64Var(&df.limit, "limit", 0, "Bandwidth limit in KB/s, 0 for none")
+	fs.DurationVar(&df.delay, "delay", df.delay, "Minimum time between requests to the same host")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	args = fs.Args()
+	missingPlaylists := lib.Missing()
+	if len(args) > 0 {
+		playlists, err := findPlaylists(lib, args)
+		if err != nil {
+			return err
+		}
+		selected := make(map[string]playlist.Playlist)
+		for _, p := range playlists {
+			if missing, ok := missingPlaylists[p.File]; ok {
+				selected[p.File] = missing
+			}
+		}
+		missingPlaylists = selected
+	}
+	if failed := downloadMissing(lib, missingPlaylists, df); failed > 0 {
+		return fmt.Errorf("%d songs failed to download", failed)
+	}
+	return nil
+}
+
+func cmdQueue(c *command, lib *library.Library, args []string) error {
+	fs := c.flagSet()
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	args = fs.Args()
+	sub := "list"
+	if len(args) > 0 {
+		sub, args = args[0], args[1:]
+	}
+	conf := lib.Config()
+	q, err := download.OpenQueue(conf.Songs)
+	if err != nil {
+		return err
+	}
+	if sub == "list" {
+		fmt.Printf("## %d songs queued, %d pending ##\n", q.Len(), q.Pending())
+		for _, it := range q.Items() {
+			fmt.Println(it.String())
+		}
+		return nil
+	}
+	match, err := queueMatch(sub, args)
+	if err != nil {
+		return err
+	}
+	switch sub {
+	case "retry":
+		fmt.Printf("%d songs will be downloaded again by the next download\n", q.Retry(match))
+	case "cancel":
+		fmt.Printf("Removed %d songs from the download queue\n", q.Remove(match))
+	}
+	return q.Save()
+}
+
+// queueMatch returns the function picking the queue items the `sub` subcommand with `args` applies to
+//
+// Items are picked by key or hash, all failed ones without any, `cancel -all` picks every item
+func queueMatch(sub string, args []string) (func(it *download.QueueItem) bool, error) {
+	switch sub {
+	case "retry":
+	case "cancel":
+		fs := flag.NewFlagSet("cancel", flag.ContinueOnError)
+		all := fs.Bool("all", false, "Cancel all queued songs")
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if *all {
+			return func(*download.QueueItem) bool { return true }, nil
+		}
+		args = fs.Args()
+	default:
+		return nil, fmt.Errorf("unknown queue subcommand %q, expected list, retry or cancel", sub)
+	}
+	return func(it *download.QueueItem) bool {
+		if len(args) == 0 {
+			return it.Failed
+		}
+		for _, id := range args {
+			if it.Matches(id) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// topFlags holds the flags shared by the top-* commands
+type topFlags struct {
+	out    string
+	backup bool
+	sel    playlist.DiffSelector
+	each   bool
+}
+
+// parseTop parses the flags and number of songs argument of the top-* commands
+func parseTop(c *command, args []string) (tf topFlags, num int, err error) {
+	fs := c.flagSet()
+	fs.StringVar(&tf.out, "o", "", "Save as this playlist file in the playlists folder, only lists songs if empty")
+	fs.BoolVar(&tf.backup, "backup", false, "Backup the playlist file if it exists")
+	diff := fs.String("diff", "max", "Rank songs by their max, min or this difficulty")
+	fs.BoolVar(&tf.each, "each", false, "List songs once per ranked difficulty")
+	if err = fs.Parse(args); err != nil {
+		return
+	}
+	if tf.sel, err = playlist.ParseDiffSelector(*diff); err != nil {
+		return
+	}
+	num, err = topCount(fs.Args())
+	return
+}
+
+// topCount parses the number of songs argument of the top-* commands
+func topCount(args []string) (int, error) {
+	if len(args) != 1 {
+		return 0, fmt.Errorf("expected number of songs as the only argument")
+	}
+	num, err := strconv.Atoi(args[0])
+	if err != nil || num < 0 {
+		return 0, fmt.Errorf("%s is not a valid number", args[0])
+	}
+	return num, nil
+}
+
+// saveTopPlaylist saves `p` as the -o file in the playlists folder of `c`, if it was given
+func saveTopPlaylist(c *library.Config, tf topFlags, p playlist.Playlist, title string) error {
+	out := tf.out
+	if out == "" {
+		return nil
+	}
+	if !playlist.IsPlaylistFile(out) {
+		out += ".bplist"
+	}
+	p.Title = title
+	p.Author = "Dre"
+	path := c.Playlists + "/" + out
+	if err := savePlaylist(path, p, tf.backup); err != nil {
+		return err
+	}
+	fmt.Printf("Saved as %s\n", path)
+	return nil
+}
+
+func cmdTopStars(c *command, lib *library.Library, args []string) error {
+	tf, num, err := parseTop(c, args)
+	if err != nil {
+		return err
+	}
+	starSongs, err := sources.DownloadStarsPlaylist(num, tf.sel, tf.each)
+	if err != nil {
+		return err
+	}
+	printStarSongs(starSongs, tf.sel)
+	conf := lib.Config()
+	return saveTopPlaylist(&conf, tf, starSongs, fmt.Sprintf("Top %d Stars", len(starSongs.Songs)))
+}
+
+func cmdTopPP(c *command, lib *library.Library, args []string) error {
+	tf, num, err := parseTop(c, args)
+	if err != nil {
+		return err
+	}
+	ppSongs, err := sources.DownloadPPPlaylist(num, tf.sel, tf.each)
+	if err != nil {
+		return err
+	}
+	printPPSongs(ppSongs, tf.sel)
+	conf := lib.Config()
+	return saveTopPlaylist(&conf, tf, ppSongs, fmt.Sprintf("Top %d PP", len(ppSongs.Songs)))
+}
+
+func cmdVerify(c *command, lib *library.Library, args []string) error {
+	fs := c.flagSet()
+	action := songAction(fs, "mismatched and failed songs")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	act, move, err := action()
+	if err != nil {
+		return err
+	}
+	ok, mismatch, fail, err := verifyLocalSongs(lib)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("## %d OK, %d mismatched, %d failed ##\n", ok, len(mismatch), len(fail))
+	if act {
+		conf := lib.Config()
+		deleteSongsFromPlaylist(&conf, playlist.Playlist{Songs: append(mismatch, fail...)}, move)
+	}
+	return nil
+}
+
+func cmdWatch(c *command, lib *library.Library, args []string) error {
+	fs := c.flagSet()
+	interval := fs.Duration("interval", library.DefaultWatchInterval,
+		"Time between checks, each one lists the files of every song folder")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *interval <= 0 {
+		return fmt.Errorf("interval must be positive")
+	}
+	ctx, stop := interruptContext()
+	defer stop()
+	numSongs, numPlaylists := lib.Counts()
+	fmt.Printf("Watching %d songs and %d playlists\n", numSongs, numPlaylists)
+	lib.Watch(ctx, *interval, func(changes []library.Change, err error) {
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return
+		}
+		for _, ch := range changes {
+			what := "song"
+			if ch.Playlist {
+				what = "playlist"
+			}
+			fmt.Printf("%s %s: %s\n", what, ch.Op, ch.Path)
+		}
+		numSongs, numPlaylists := lib.Counts()
+		fmt.Printf("-> %d songs, %d playlists, %d missing\n", numSongs, numPlaylists, countMissing(lib.Missing()))
+	})
+	return nil
+}
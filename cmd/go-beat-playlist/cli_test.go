@@ -0,0 +1,114 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"testing"
+
+	"github.com/cosandr/go-beat-playlist/download"
+)
+
+func TestTopCount(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected int
+		err      bool
+	}{
+		{[]string{"50"}, 50, false},
+		{[]string{"0"}, 0, false},
+		{[]string{"-1"}, 0, true},
+		{[]string{"fifty"}, 0, true},
+		{[]string{"1.5"}, 0, true},
+		{nil, 0, true},
+		{[]string{"10", "20"}, 0, true},
+	}
+	for _, tt := range tests {
+		num, err := topCount(tt.args)
+		if (err != nil) != tt.err {
+			t.Errorf("%v: expected error %v, got %v", tt.args, tt.err, err)
+		} else if num != tt.expected {
+			t.Errorf("%v: expected %d, got %d", tt.args, tt.expected, num)
+		}
+	}
+}
+
+func TestSongAction(t *testing.T) {
+	tests := []struct {
+		args []string
+		act  bool
+		move bool
+		err  bool
+	}{
+		{nil, false, false, false},
+		{[]string{"-delete"}, true, false, false},
+		{[]string{"-move"}, true, true, false},
+		{[]string{"-delete", "-move"}, false, false, true},
+		{[]string{"-move", "-delete=false"}, true, true, false},
+	}
+	for _, tt := range tests {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(ioutil.Discard)
+		action := songAction(fs, "songs")
+		if err := fs.Parse(tt.args); err != nil {
+			t.Fatalf("%v: parse failed: %v", tt.args, err)
+		}
+		act, move, err := action()
+		if (err != nil) != tt.err {
+			t.Errorf("%v: expected error %v, got %v", tt.args, tt.err, err)
+		} else if act != tt.act || move != tt.move {
+			t.Errorf("%v: expected act %v and move %v, got %v and %v", tt.args, tt.act, tt.move, act, move)
+		}
+	}
+}
+
+func TestQueueMatch(t *testing.T) {
+	items := []*download.QueueItem{
+		{Hash: "abc", Key: "1a2b"},
+		{Hash: "def", Key: "3c4d", Failed: true},
+		{Hash: "123", Failed: true},
+	}
+	tests := []struct {
+		sub  string
+		args []string
+		// expected are the indexes of the matched items
+		expected []int
+		err      bool
+	}{
+		{"retry", nil, []int{1, 2}, false},
+		{"retry", []string{"1A2B"}, []int{0}, false},
+		{"cancel", nil, []int{1, 2}, false},
+		{"cancel", []string{"-all"}, []int{0, 1, 2}, false},
+		{"cancel", []string{"-all", "abc"}, []int{0, 1, 2}, false},
+		{"cancel", []string{"DEF", "123"}, []int{1, 2}, false},
+		// Flags must come before IDs
+		{"cancel", []string{"abc", "-all"}, []int{0}, false},
+		{"cancel", []string{"-unknown"}, nil, true},
+		{"delete", nil, nil, true},
+	}
+	for _, tt := range tests {
+		match, err := queueMatch(tt.sub, tt.args)
+		if (err != nil) != tt.err {
+			t.Errorf("%s %v: expected error %v, got %v", tt.sub, tt.args, tt.err, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		var matched []int
+		for i, it := range items {
+			if match(it) {
+				matched = append(matched, i)
+			}
+		}
+		if len(matched) != len(tt.expected) {
+			t.Errorf("%s %v: expected items %v, got %v", tt.sub, tt.args, tt.expected, matched)
+			continue
+		}
+		for i := range matched {
+			if matched[i] != tt.expected[i] {
+				t.Errorf("%s %v: expected items %v, got %v", tt.sub, tt.args, tt.expected, matched)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+
+	"github.com/cosandr/go-beat-playlist/download"
+	"github.com/cosandr/go-beat-playlist/internal/fsutil"
+	"github.com/cosandr/go-beat-playlist/library"
+	"github.com/cosandr/go-beat-playlist/playlist"
+	"github.com/cosandr/go-beat-playlist/sources"
+	log "github.com/sirupsen/logrus"
+)
+
+func printAllPlaylists(lib *library.Library) {
+	for _, p := range lib.Playlists() {
+		fmt.Println(p.String())
+	}
+}
+
+// savePlaylist writes `p` to `path`, renaming an existing file to .bak first if `backup` is set
+func savePlaylist(path string, p playlist.Playlist, backup bool) error {
+	if backup && fsutil.FileExists(path) {
+		err := os.Rename(path, playlist.BackupPath(path))
+		if err != nil {
+			return fmt.Errorf("cannot backup %s: %v", path, err)
+		}
+	}
+	err := ioutil.WriteFile(path, p.ToJSON(), 0755)
+	if err != nil {
+		return fmt.Errorf("cannot write playlist: %v", err)
+	}
+	return nil
+}
+
+// addToPlaylist writes the songs in `p` to the playlist at `path`
+//
+// If the file exists and `merge` is set, the songs are merged into the existing playlist
+func addToPlaylist(path string, p playlist.Playlist, merge bool) error {
+	writePlaylist := p
+	if merge && fsutil.FileExists(path) {
+		existing, err := playlist.MakePlaylist(path)
+		if err != nil {
+			return fmt.Errorf("cannot read playlist: %v", err)
+		}
+		writePlaylist = existing.Merge(&p)
+	}
+	return savePlaylist(path, writePlaylist, false)
+}
+
+// pruneMissing rewrites the playlists in `missing`, keeping only installed songs and official levels
+func pruneMissing(lib *library.Library, missing map[string]playlist.Playlist, backup bool) error {
+	var failed int
+	for path, p := range missing {
+		full, _ := lib.Playlist(path)
+		songs := []playlist.Song{}
+		for _, s := range full.Songs {
+			if s.Path != "" || s.IsBuiltIn() {
+				songs = append(songs, s)
+			}
+		}
+		if len(songs) == 0 {
+			continue
+		}
+		// Start from the full playlist, so fields we do not use are written back
+		writePlaylist := full
+		writePlaylist.Songs = songs
+		if err := savePlaylist(p.File, writePlaylist, backup); err != nil {
+			fmt.Printf("%s: %v\n", p.Title, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d playlists could not be written", failed)
+	}
+	return nil
+}
+
+// interruptContext returns a context cancelled on interrupt, call `stop` once done with it
+func interruptContext() (ctx context.Context, stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	go func() {
+		select {
+		case <-sig:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(sig)
+		cancel()
+	}
+}
+
+// downloadFlags configures the download manager
+type downloadFlags struct {
+	parallel int
+	// limit is the bandwidth limit in KB/s, zero for none
+	limit int64
+	delay time.Duration
+}
+
+// defaultDownloadFlags are used by the main menu
+var defaultDownloadFlags = downloadFlags{parallel: download.DefaultWorkers, delay: download.DefaultHostDelay}
+
+// downloadMissing downloads all songs in `missing` to the songs folder of `lib`, returns the number of failed downloads
+//
+// Songs listed in several playlists are only downloaded once, the download stops early on interrupt. Songs left in
+// the download queue by earlier runs are downloaded too, unless they were installed since.
+func downloadMissing(lib *library.Library, missing map[string]playlist.Playlist, df downloadFlags) (failed int) {
+	c := lib.Config()
+	m := download.NewManager(c.Songs)
+	m.SetWorkers(df.parallel)
+	m.SetBandwidth(df.limit * 1024)
+	m.SetHostDelay(df.delay)
+	q, err := download.OpenQueue(c.Songs)
+	if err != nil {
+		fmt.Printf("Cannot read download queue, interrupted downloads will not be resumed: %v\n", err)
+	} else {
+		q.Remove(func(it *download.QueueItem) bool {
+			s := it.Song()
+			_, ok := lib.Installed(&s)
+			return ok
+		})
+		m.SetQueue(q)
+		if m.Len() > 0 {
+			fmt.Printf("--> Resuming %d queued songs\n", m.Len())
+		}
+	}
+	m.AddMissing(missing)
+	if m.Len() == 0 {
+		fmt.Println("Nothing to download")
+		return
+	}
+	if err := m.CheckSpace(); err != nil {
+		fmt.Printf("Not downloading, %v\n", err)
+		return m.Len()
+	}
+	fmt.Printf("--> Downloading %d songs\n", m.Len())
+	m.SetProgress(func(r download.Result, done int, total int) {
+		if r.Err != nil {
+			fmt.Printf(" [%d/%d] Failed %s: %v\n", done, total, r.Song.String(), r.Err)
+		} else {
+			fmt.Printf(" [%d/%d] Downloaded %s\n", done, total, r.Song.String())
+		}
+	})
+	ctx, stop := interruptContext()
+	defer stop()
+	sum := m.Run(ctx)
+	fmt.Print(sum.String())
+	if q != nil && q.Len() > 0 {
+		fmt.Printf("%d songs left in the download queue, see the queue command\n", q.Len())
+	}
+	return len(sum.Failed)
+}
+
+// verifyLocalSongs compares installed songs with scraped data
+//
+// Returns the number of songs found by hash, songs only matched by name and songs not found at all
+func verifyLocalSongs(lib *library.Library) (ok int, mismatch []playlist.Song, fail []playlist.Song, err error) {
+	allSongs, err := sources.DownloadScrapedData(false)
+	if err != nil {
+		err = fmt.Errorf("cannot download scraped data: %v", err)
+		return
+	}
+	scraped := allSongs.Index()
+	isOK := func(other playlist.Song) rune {
+		// Look for hash
+		if other.Hash != "" {
+			if _, found := scraped.Find(&playlist.Song{Hash: other.Hash}); found {
+				return 'o'
+			}
+		}
+		// Check for name matches, scraped data has an extra ' - ' at the end
+		for _, s := range scraped.FindByName(other.Name) {
+			log.Debugf("%s name match with %s\n", s.Name, other.Name)
+			// Only if the author matches as well
+			if s.Mapper == other.Mapper || s.Mapper == other.Author {
+				// Maybe try to download song info from ScoreSaber as well?
+				// Scraped data might be out of date
+				return 'm'
+			}
+		}
+		return 'f'
+	}
+	for _, s := range lib.Songs().Songs {
+		switch isOK(s) {
+		case 'o':
+			ok++
+		case 'm':
+			mismatch = append(mismatch, s)
+			fmt.Printf("-> Mismatch: %s\n", s.String())
+		case 'f':
+			fail = append(fail, s)
+			fmt.Printf("-> Cannot find: %s\n", s.String())
+		}
+	}
+	return
+}
+
+// printProgress returns a library.ProgressFunc printing the scan progress on a single stderr line
+func printProgress() library.ProgressFunc {
+	var mu sync.Mutex
+	var last int
+	return func(done int, total int, path string) {
+		mu.Lock()
+		defer mu.Unlock()
+		// Calls can arrive out of order, never go backwards
+		if done <= last {
+			return
+		}
+		last = done
+		fmt.Fprintf(os.Stderr, "\rScanning songs %d/%d", done, total)
+		if done == total {
+			fmt.Fprintln(os.Stderr)
+			last = 0
+		}
+	}
+}
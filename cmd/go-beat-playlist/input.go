@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/cosandr/go-beat-playlist/internal/fsutil"
+	"github.com/cosandr/go-beat-playlist/library"
+)
+
+// GetInputNumber returns first valid number from user input
+//
+// Returns io.EOF once input ends, such as after Ctrl-D
+func GetInputNumber() (int, error) {
+	return readNumber(bufio.NewScanner(os.Stdin))
+}
+
+// readNumber returns the first valid number read by `scanner`, io.EOF if there is none
+func readNumber(scanner *bufio.Scanner) (int, error) {
+	for scanner.Scan() {
+		num, err := strconv.Atoi(scanner.Text())
+		if err != nil || num < 0 {
+			fmt.Printf("%s is not a valid number, try again: ", scanner.Text())
+			continue
+		}
+		return num, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, io.EOF
+}
+
+// GetInputPlaylist returns complete path
+func GetInputPlaylist(dirPath string) (path string, exists bool) {
+	scanner := bufio.NewScanner(os.Stdin)
+	fmt.Print("Enter playlist file: ")
+	for scanner.Scan() {
+		path = dirPath + "/" + scanner.Text()
+		exists = fsutil.FileExists(path)
+		return
+	}
+	return
+}
+
+// GetConfirm reads y/n answer and returns boolean, defaults to true (empty returns true)
+func GetConfirm(question string) bool {
+	scanner := bufio.NewScanner(os.Stdin)
+	fmt.Print(question)
+	for scanner.Scan() {
+		if len(scanner.Text()) == 0 {
+			return true
+		} else if strings.ToLower(scanner.Text()) == "y" {
+			return true
+		} else if strings.ToLower(scanner.Text()) == "n" {
+			return false
+		} else {
+			fmt.Printf("%s is not a valid response, try again: ", scanner.Text())
+			continue
+		}
+	}
+	return false
+}
+
+// askGame returns a library.AskGameFunc asking for the game folder on stdin
+//
+// Fails if stdin is not a terminal, `configPath` is only used in error messages
+func askGame(configPath string) library.AskGameFunc {
+	return func(notFound string, candidates []string) (string, error) {
+		hint := fmt.Sprintf("set it with -game, %sGAME or in %s", library.EnvPrefix, configPath)
+		if !IsTerminal(os.Stdin) {
+			if len(candidates) > 0 {
+				return "", fmt.Errorf("found several games (%s), %s", strings.Join(candidates, ", "), hint)
+			}
+			return "", fmt.Errorf("game not found at %s, %s", notFound, hint)
+		}
+		if len(candidates) > 0 {
+			fmt.Println("Found several games:")
+			for i, g := range candidates {
+				fmt.Printf("%d: %s\n", i+1, g)
+			}
+			for {
+				fmt.Print("Select game: ")
+				in, err := GetInputNumber()
+				if err != nil {
+					return "", fmt.Errorf("no game selected: %v", err)
+				}
+				if in > 0 && in <= len(candidates) {
+					return candidates[in-1], nil
+				}
+				fmt.Println("Invalid option")
+			}
+		}
+		fmt.Printf("game not found at %s, enter game path: ", notFound)
+		scanner := bufio.NewScanner(os.Stdin)
+		if !scanner.Scan() {
+			return "", fmt.Errorf("game not found at %s", notFound)
+		}
+		return scanner.Text(), nil
+	}
+}
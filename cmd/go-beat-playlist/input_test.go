@@ -0,0 +1,28 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestReadNumber(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected int
+		err      error
+	}{
+		{"3\n", 3, nil},
+		{"abc\n-1\n2\n", 2, nil},
+		{"", 0, io.EOF},
+		// Input ending without a valid number, as with Ctrl-D
+		{"abc\n", 0, io.EOF},
+	}
+	for _, tt := range tests {
+		num, err := readNumber(bufio.NewScanner(strings.NewReader(tt.in)))
+		if err != tt.err || num != tt.expected {
+			t.Errorf("%q: expected %d and %v, got %d and %v", tt.in, tt.expected, tt.err, num, err)
+		}
+	}
+}
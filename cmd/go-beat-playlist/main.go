@@ -0,0 +1,412 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/cosandr/go-beat-playlist/internal/fsutil"
+	"github.com/cosandr/go-beat-playlist/library"
+	"github.com/cosandr/go-beat-playlist/playlist"
+	"github.com/cosandr/go-beat-playlist/sources"
+	log "github.com/sirupsen/logrus"
+)
+
+func mainMenu(lib *library.Library) {
+	const helpText = `Beat Saber playlist editor written in Go
+
+1: Show all read playlists and their songs
+2: Show all installed song data
+3: Songs not in any playlists
+4: Songs missing from playlists
+5: Create playlist sorted by ScoreSaber star difficulty
+6: Create playlist sorted by PP using Song Browser data
+7: Check local song hashes
+0: Exit`
+	for first := true; ; first = false {
+		// Pick up changes made by the last action or outside of this program
+		if !first {
+			reload(lib)
+		}
+		fmt.Printf("%s\n", helpText)
+		if lib.Config().Profile != "" {
+			fmt.Printf("Profile %s: ", lib.Config().Profile)
+		}
+		numSongs, numPlaylists := lib.Counts()
+		fmt.Printf("Loaded %d songs and %d playlists.\n", numSongs, numPlaylists)
+		fmt.Print("Select option: ")
+		in, err := GetInputNumber()
+		if err != nil {
+			return
+		}
+		fmt.Println()
+		switch in {
+		case 0:
+			return
+		case 1:
+			printAllPlaylists(lib)
+		case 2:
+			songs := lib.Songs()
+			fmt.Println(songs.String())
+		case 3:
+			songsWithoutPlaylists(lib)
+		case 4:
+			missingFromPlaylists(lib)
+		case 5:
+			songsFromScoreSaber(lib)
+		case 6:
+			songsFromSongBrowser(lib)
+		case 7:
+			// Check hashes
+			checkLocalSongs(lib)
+		default:
+			fmt.Println("Invalid option")
+		}
+	}
+}
+
+// reload updates `lib` with what changed after an action, printing any error
+func reload(lib *library.Library) {
+	changes, err := lib.Refresh()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	log.Debugf("%d songs or playlists changed", len(changes))
+}
+
+func checkLocalSongs(lib *library.Library) {
+	c := lib.Config()
+	ok, mismatch, fail, err := verifyLocalSongs(lib)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	var helpText = `## %d OK, %d mismatched, %d failed ##
+
+1: Delete mismatches and failed
+0: Back to main menu`
+	fmt.Printf(helpText, ok, len(mismatch), len(fail))
+	fmt.Println()
+	fmt.Print("Select option: ")
+	in, err := GetInputNumber()
+	if err != nil {
+		return
+	}
+	switch in {
+	case 0:
+		return
+	case 1:
+		if len(mismatch) > 0 {
+			move := GetConfirm("Move mismatches to DeletedSongs instead of deleting? (Y/n) ")
+			deleteSongsFromPlaylist(&c, playlist.Playlist{Songs: mismatch}, move)
+		}
+		if len(fail) > 0 {
+			move := GetConfirm("Move failed songs to DeletedSongs instead of deleting? (Y/n) ")
+			deleteSongsFromPlaylist(&c, playlist.Playlist{Songs: fail}, move)
+		}
+	}
+}
+
+func songsFromSongBrowser(lib *library.Library) {
+	c := lib.Config()
+	var helpText = `## %d songs from Song Browser data ##
+
+1: Show songs
+2: Add to playlist
+0: Back to main menu`
+	fmt.Print("Enter max number of songs to fetch: ")
+	numSongs, err := GetInputNumber()
+	if err != nil {
+		return
+	}
+	ppSongs, err := (sources.DownloadPPPlaylist(numSongs, playlist.DiffSelector{}, false))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	for {
+		numSongs = len((ppSongs).Songs)
+		fmt.Printf(helpText, numSongs)
+		fmt.Println()
+		fmt.Print("Select option: ")
+		in, err := GetInputNumber()
+		if err != nil {
+			return
+		}
+		switch in {
+		case 0:
+			return
+		case 1:
+			printPPSongs(ppSongs, playlist.DiffSelector{})
+		case 2:
+			path := fmt.Sprintf("Top%dPP.bplist", numSongs)
+			fmt.Printf("Saving as %s\n", path)
+			path = fmt.Sprintf("%s/%s", c.Playlists, path)
+			backup := fsutil.FileExists(path) && GetConfirm("Backup existing file? (Y/n) ")
+			ppSongs.Title = fmt.Sprintf("Top %d PP", numSongs)
+			ppSongs.Author = "Dre"
+			if err := savePlaylist(path, ppSongs, backup); err != nil {
+				fmt.Println(err)
+				continue
+			}
+			return
+		}
+	}
+}
+
+func songsFromScoreSaber(lib *library.Library) {
+	c := lib.Config()
+	var helpText = `## %d songs from ScoreSaber ##
+
+1: Show songs
+2: Add to playlist
+0: Back to main menu`
+	fmt.Print("Enter max number of songs to fetch: ")
+	numSongs, err := GetInputNumber()
+	if err != nil {
+		return
+	}
+	starSongs, err := (sources.DownloadStarsPlaylist(numSongs, playlist.DiffSelector{}, false))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	for {
+		numSongs = len((starSongs).Songs)
+		fmt.Printf(helpText, numSongs)
+		fmt.Println()
+		fmt.Print("Select option: ")
+		in, err := GetInputNumber()
+		if err != nil {
+			return
+		}
+		switch in {
+		case 0:
+			return
+		case 1:
+			printStarSongs(starSongs, playlist.DiffSelector{})
+		case 2:
+			path := fmt.Sprintf("Top%dStars.bplist", numSongs)
+			fmt.Printf("Saving as %s\n", path)
+			path = fmt.Sprintf("%s/%s", c.Playlists, path)
+			backup := fsutil.FileExists(path) && GetConfirm("Backup existing file? (Y/n) ")
+			starSongs.Title = fmt.Sprintf("Top %d Stars", numSongs)
+			starSongs.Author = "Dre"
+			if err := savePlaylist(path, starSongs, backup); err != nil {
+				fmt.Println(err)
+				continue
+			}
+			return
+		}
+	}
+}
+
+func printPPSongs(p playlist.Playlist, sel playlist.DiffSelector) {
+	for _, s := range p.Songs {
+		fmt.Printf("-> %.2f PP: %s%s\n", s.PPFor(sel), s.Name, rankedDiffs(&s))
+	}
+}
+
+func printStarSongs(p playlist.Playlist, sel playlist.DiffSelector) {
+	for _, s := range p.Songs {
+		fmt.Printf("-> %.2f stars: %s%s\n", s.StarsFor(sel), s.Name, rankedDiffs(&s))
+	}
+}
+
+// rankedDiffs returns the ranked difficulties of `s` in brackets, empty if it has none
+func rankedDiffs(s *playlist.Song) string {
+	var diffs []string
+	for _, bm := range s.Maps {
+		if bm.Ranked {
+			diffs = append(diffs, bm.Difficulty.Pretty())
+		}
+	}
+	if len(diffs) == 0 {
+		return ""
+	}
+	return " [" + strings.Join(diffs, ", ") + "]"
+}
+
+// deleteSongsFromPlaylist deletes all songs in `p`, or moves them to the DeletedSongs folder of `c`
+func deleteSongsFromPlaylist(c *library.Config, p playlist.Playlist, move bool) {
+	for _, s := range p.Songs {
+		if !move {
+			err := os.RemoveAll(s.Path)
+			if err != nil {
+				fmt.Printf("Cannot delete %s: %v\n", s.String(), err)
+				continue
+			}
+			fmt.Printf("Deleted %s\n", s.String())
+		} else {
+			err := os.Rename(s.Path, fmt.Sprintf("%s/%s", c.DeletedSongs, s.DirName()))
+			if err != nil {
+				fmt.Printf("Cannot move %s: %v\n", s.String(), err)
+				continue
+			}
+			fmt.Printf("Moved %s\n", s.String())
+		}
+	}
+}
+
+// songsWithoutPlaylists provides the UX for handling songs without playlists
+func songsWithoutPlaylists(lib *library.Library) {
+	c := lib.Config()
+	var helpText = `## %d songs without playlists ##
+
+1: Show songs
+2: Add to playlist
+3: Move or delete all
+0: Back to main menu`
+	for {
+		orphansPlaylist := lib.Orphans()
+		fmt.Printf(helpText, len((orphansPlaylist).Songs))
+		fmt.Println()
+		fmt.Print("Select option: ")
+		in, err := GetInputNumber()
+		if err != nil {
+			return
+		}
+		fmt.Println()
+		switch in {
+		case 0:
+			return
+		case 1:
+			fmt.Print(orphansPlaylist.String())
+		case 2:
+			// Ask for playlist path
+			path, exists := GetInputPlaylist(c.Playlists)
+			// Confirm override
+			merging := exists && GetConfirm("File already exists, merge? (Y/n) ")
+			if merging {
+				fmt.Println("Merging orphans with playlist")
+			} else {
+				fmt.Println("Writing new playlist")
+			}
+			if err := addToPlaylist(path, orphansPlaylist, merging); err != nil {
+				fmt.Println(err)
+				continue
+			}
+			return
+		case 3:
+			move := GetConfirm("Move to DeletedSongs instead of deleting? (Y/n) ")
+			deleteSongsFromPlaylist(&c, orphansPlaylist, move)
+			return
+		default:
+			fmt.Println("Invalid option")
+		}
+	}
+}
+
+func missingFromPlaylists(lib *library.Library) {
+	var helpText = `## %d songs missing from all playlists ##
+
+%s
+1: Show songs
+2: Remove from playlists
+3: Download
+0: Back to main menu`
+	for {
+		missingPlaylists := lib.Missing()
+		fmt.Printf(helpText, countMissing(missingPlaylists), missingSummary(missingPlaylists))
+		fmt.Println()
+		fmt.Print("Select option: ")
+		in, err := GetInputNumber()
+		if err != nil {
+			return
+		}
+		fmt.Println()
+		switch in {
+		case 0:
+			return
+		case 1:
+			for _, p := range missingPlaylists {
+				fmt.Println(p.String())
+			}
+		case 2:
+			for path, p := range missingPlaylists {
+				backup := GetConfirm(fmt.Sprintf("Backup %s? (Y/n) ", p.Title))
+				if err := pruneMissing(lib, map[string]playlist.Playlist{path: p}, backup); err != nil {
+					fmt.Println(err)
+				}
+			}
+			return
+		case 3:
+			downloadMissing(lib, missingPlaylists, defaultDownloadFlags)
+			return
+		}
+	}
+}
+
+// countMissing returns the total number of songs in `missing`
+func countMissing(missing map[string]playlist.Playlist) (total int) {
+	for _, p := range missing {
+		total += len(p.Songs)
+	}
+	return
+}
+
+// missingSummary returns one line per playlist with its number of missing songs
+func missingSummary(missing map[string]playlist.Playlist) (ret string) {
+	for _, p := range missing {
+		ret += fmt.Sprintf("-> %d from %s\n", len(p.Songs), p.Title)
+	}
+	return
+}
+
+func main() {
+	var debug bool
+	var configPath string
+	var profile string
+	var override library.ProfileJSON
+	var jobs int
+	var cachePath string
+
+	// Parse arguments
+	flag.BoolVar(&debug, "debug", false, "Debug logging")
+	flag.StringVar(&configPath, "config", "", "Config file path, overrides "+library.EnvPrefix+"CONFIG")
+	flag.StringVar(&profile, "profile", os.Getenv(library.EnvPrefix+"PROFILE"), "Config profile, defaults to "+library.EnvPrefix+"PROFILE or the config's defaultProfile")
+	flag.StringVar(&override.Game, "game", "", "Game folder, overrides "+library.EnvPrefix+"GAME")
+	flag.StringVar(&override.Songs, "songs", "", "Custom songs folder, overrides "+library.EnvPrefix+"SONGS")
+	flag.StringVar(&override.Playlists, "playlists", "", "Playlists folder, overrides "+library.EnvPrefix+"PLAYLISTS")
+	flag.StringVar(&override.DeletedSongs, "deleted", "", "Deleted songs folder, overrides "+library.EnvPrefix+"DELETED")
+	flag.IntVar(&jobs, "jobs", runtime.NumCPU(), "Number of songs scanned and hashed in parallel")
+	flag.StringVar(&cachePath, "cache", library.DefaultCachePath(), "Song hash cache file, empty disables caching")
+	flag.Usage = usage
+	flag.Parse()
+	if debug {
+		log.SetLevel(log.DebugLevel)
+	}
+
+	configPath = library.FindConfig(configPath)
+	log.Debugf("Using config %s", configPath)
+	c, err := library.NewConfig(configPath, profile, library.ConfigFromEnv().Override(override), askGame(configPath))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	if c.Profile != "" {
+		log.Debugf("Using profile %s", c.Profile)
+	}
+
+	lib := library.NewLibrary(c)
+	lib.SetWorkers(jobs)
+	lib.SetCachePath(cachePath)
+	if IsTerminal(os.Stderr) {
+		lib.SetProgress(printProgress())
+	}
+	if err = lib.Load(); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	if flag.NArg() > 0 {
+		if err := runCommand(lib, flag.Args()); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	mainMenu(lib)
+}
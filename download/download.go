@@ -0,0 +1,140 @@
+// Package download downloads songs from BeatSaver into a songs folder
+package download
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cosandr/go-beat-playlist/internal/fsutil"
+	"github.com/cosandr/go-beat-playlist/internal/web"
+	"github.com/cosandr/go-beat-playlist/playlist"
+	"github.com/cosandr/go-beat-playlist/sources"
+)
+
+// beatSaverURL is prepended to the download URLs of songs
+var beatSaverURL = "https://beatsaver.com"
+
+// StagingDir is the folder in the songs folder where downloads are kept until they are verified
+const StagingDir = ".staging"
+
+// DownloadSong tries to download a song from BeatSaver using its hash or key, returns a DownloadSong
+//
+// Function merges downloaded metadata with argument, downloaded song is saved in the `songsDir` folder
+func DownloadSong(s *playlist.Song, songsDir string) (retSong playlist.Song, err error) {
+	return DownloadSongContext(context.Background(), s, songsDir)
+}
+
+// DownloadSongContext is DownloadSong, cancelled when `ctx` is done
+//
+// The song is downloaded and extracted in StagingDir and only moved into `songsDir` once its hash matches. A song
+// folder which is already installed is checked but never replaced.
+func DownloadSongContext(ctx context.Context, s *playlist.Song, songsDir string) (retSong playlist.Song, err error) {
+	if s.IsBuiltIn() {
+		err = fmt.Errorf("%s is an official level, it cannot be downloaded", s.LevelID)
+		return
+	}
+	// Working Song
+	var dlSong playlist.Song
+	if len(s.URL) == 0 {
+		bsSong, errDl := sources.DownloadSongInfoContext(ctx, s)
+		if errDl != nil {
+			err = errDl
+			return
+		}
+		dlSong = bsSong
+	} else {
+		dlSong = *s
+	}
+	dirName := dlSong.DirName()
+	if dirName == "" || dirName == "." || dirName == ".." {
+		err = fmt.Errorf("%s has no valid folder name", dlSong.String())
+		return
+	}
+	dlPath := filepath.Join(songsDir, dirName)
+	if fsutil.DirExists(dlPath) {
+		infoPath, errI := playlist.FindInfo(dlPath)
+		if errI != nil {
+			err = errI
+			return
+		}
+		if retSong, err = playlist.MakeSong(infoPath); err != nil {
+			return
+		}
+		if !strings.EqualFold(dlSong.Hash, retSong.Hash) {
+			err = fmt.Errorf("%s is already installed with hash %s, expected %s", dlPath, retSong.Hash, dlSong.Hash)
+			return
+		}
+	} else if retSong, err = install(ctx, &dlSong, songsDir, dlPath); err != nil {
+		return
+	}
+	retSong = retSong.Merge(&dlSong)
+	return
+}
+
+// install downloads `s` into the staging folder of `songsDir`, checks its hash and moves it to `dlPath`
+func install(ctx context.Context, s *playlist.Song, songsDir string, dlPath string) (retSong playlist.Song, err error) {
+	staging := filepath.Join(songsDir, StagingDir)
+	if err = os.MkdirAll(staging, 0755); err != nil {
+		return
+	}
+	f, err := ioutil.TempFile(staging, "*.zip")
+	if err != nil {
+		return
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	size, err := web.FetchFile(ctx, beatSaverURL+s.URL, f)
+	if err != nil {
+		return
+	}
+	zipReader, err := zip.NewReader(f, size)
+	if err != nil {
+		err = fmt.Errorf("%s: %v", s.String(), err)
+		return
+	}
+	tmpDir, err := ioutil.TempDir(staging, "song-")
+	if err != nil {
+		return
+	}
+	defer os.RemoveAll(tmpDir)
+	if err = extract(tmpDir, zipReader); err != nil {
+		return
+	}
+	infoPath, err := playlist.FindInfo(tmpDir)
+	if err != nil {
+		return
+	}
+	if infoPath == "" {
+		err = fmt.Errorf("%s: download has no info.dat", s.String())
+		return
+	}
+	retSong, err = playlist.MakeSong(infoPath)
+	if err != nil {
+		return
+	}
+	if !strings.EqualFold(s.Hash, retSong.Hash) {
+		err = fmt.Errorf("download failed, hash mismatch: expected %s, got %s", s.Hash, retSong.Hash)
+		return
+	}
+	// Songs zipped inside a folder are installed from that folder
+	if err = os.Rename(filepath.Dir(infoPath), dlPath); err != nil {
+		return
+	}
+	retSong.Path = filepath.ToSlash(dlPath)
+	return
+}
+
+// DownloadSongBytes tries to download a song from BeatSaver using its url, returns byte array
+func DownloadSongBytes(url string) (out []byte, err error) {
+	return DownloadSongBytesContext(context.Background(), url)
+}
+
+// DownloadSongBytesContext is DownloadSongBytes, cancelled when `ctx` is done
+func DownloadSongBytesContext(ctx context.Context, url string) (out []byte, err error) {
+	return web.Fetch(ctx, beatSaverURL+url)
+}
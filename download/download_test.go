@@ -0,0 +1,92 @@
+package download
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cosandr/go-beat-playlist/internal/fsutil"
+	"github.com/cosandr/go-beat-playlist/playlist"
+)
+
+func TestDownloadSong(t *testing.T) {
+	dir, err := ioutil.TempDir("", "songs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s := playlist.Song{Hash: "9bf202f68c333421c69ca6aa15c648d65d4a1e0f", Name: "Night Raid"}
+	out, err := DownloadSong(&s, dir)
+	if err != nil {
+		t.Errorf("Song download failed: %v", err)
+	} else {
+		t.Logf("Song download successful\n%s", out.Debug())
+	}
+}
+
+func TestDownloadStaging(t *testing.T) {
+	// Serve the sample song zipped inside a folder
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	files, err := ioutil.ReadDir("../samples/song-v4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fi := range files {
+		data, err := ioutil.ReadFile("../samples/song-v4/" + fi.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		f, _ := w.Create("song/" + fi.Name())
+		f.Write(data)
+	}
+	w.Close()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(buf.Bytes())
+	}))
+	defer srv.Close()
+	beatSaverURL = srv.URL
+	defer func() { beatSaverURL = "https://beatsaver.com" }()
+
+	sample, err := playlist.MakeSong("../samples/song-v4/Info.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "songs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	bad := playlist.Song{Key: "1", Name: "Bad", Hash: strings.Repeat("0", 40), URL: "/bad.zip"}
+	if _, err = DownloadSong(&bad, dir); err == nil {
+		t.Error("Expected a hash mismatch")
+	}
+	if fsutil.DirExists(filepath.Join(dir, bad.DirName())) {
+		t.Error("Song with a mismatched hash was installed")
+	}
+	s := playlist.Song{Key: "2", Name: "Good", Hash: strings.ToUpper(sample.Hash), URL: "/good.zip"}
+	out, err := DownloadSong(&s, dir)
+	if err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	if expected := filepath.ToSlash(filepath.Join(dir, s.DirName())); out.Path != expected {
+		t.Errorf("Expected song in %s, got %s", expected, out.Path)
+	}
+	if !fsutil.FileExists(filepath.Join(dir, s.DirName(), "Info.dat")) {
+		t.Error("Info.dat was not installed at the top of the song folder")
+	}
+	if staged, _ := ioutil.ReadDir(filepath.Join(dir, StagingDir)); len(staged) != 0 {
+		t.Errorf("Expected staging to be empty, found %d files", len(staged))
+	}
+	// Installed songs are checked, not downloaded again
+	if _, err = DownloadSong(&s, dir); err != nil {
+		t.Errorf("Installed song failed: %v", err)
+	}
+}
@@ -0,0 +1,138 @@
+package download
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// MaxFileSize is the largest file we extract from a song
+	MaxFileSize = 200 << 20
+	// MaxExtractSize is the largest a song may be once extracted
+	MaxExtractSize = 500 << 20
+	// MaxFiles is the most files a song may have
+	MaxFiles = 2000
+)
+
+// songExts are the file types a song is made of, other files are never extracted
+var songExts = map[string]bool{
+	// Info, beatmaps, lightshows, audio data and BPM files, mod settings such as Cinema's
+	".dat": true, ".json": true,
+	".egg": true, ".ogg": true, ".wav": true,
+	".png": true, ".jpg": true, ".jpeg": true,
+	// Lyrics and readmes
+	".srt": true, ".txt": true,
+}
+
+// ExtractZIP extract byte slice (ZIP file) to `path`
+//
+// See extract for which files are written, files extracted before an error are left in `path`
+func ExtractZIP(path string, in *[]byte) (err error) {
+	if err = os.MkdirAll(path, 0755); err != nil {
+		return
+	}
+	zipReader, err := zip.NewReader(bytes.NewReader(*in), int64(len(*in)))
+	if err != nil {
+		return
+	}
+	return extract(path, zipReader)
+}
+
+// extract writes the files of `zipReader` to `dest`, keeping their folders
+//
+// Archives with paths outside of `dest`, too many files, or files larger than MaxFileSize or MaxExtractSize in
+// total are rejected. Links and files which are not song assets, see songExts, are skipped.
+func extract(dest string, zipReader *zip.Reader) (err error) {
+	if len(zipReader.File) > MaxFiles {
+		return fmt.Errorf("archive has %d files, at most %d are allowed", len(zipReader.File), MaxFiles)
+	}
+	// Check everything before writing anything, sizes are checked again while extracting as they can lie
+	var total uint64
+	targets := make([]string, len(zipReader.File))
+	for i, zf := range zipReader.File {
+		if targets[i], err = extractPath(dest, zf.Name); err != nil {
+			return
+		}
+		if zf.UncompressedSize64 > MaxFileSize {
+			return fmt.Errorf("%s is larger than %d MiB", zf.Name, MaxFileSize>>20)
+		}
+		total += zf.UncompressedSize64
+	}
+	if total > MaxExtractSize {
+		return fmt.Errorf("archive is larger than %d MiB extracted", MaxExtractSize>>20)
+	}
+	var written int64
+	for i, zf := range zipReader.File {
+		mode := zf.Mode()
+		switch {
+		case mode.IsDir():
+			err = os.MkdirAll(targets[i], 0755)
+		case !mode.IsRegular():
+			log.Warnf("Skipping %s, it is not a regular file", zf.Name)
+		case !songExts[strings.ToLower(path.Ext(zf.Name))]:
+			log.Warnf("Skipping %s, it is not a song file", zf.Name)
+		default:
+			var n int64
+			n, err = extractFile(zf, targets[i], MaxExtractSize-written)
+			written += n
+		}
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// extractPath returns where the archive file `name` is extracted in `dest`
+//
+// Absolute paths and paths leaving `dest` are errors, backslashes are taken as separators
+func extractPath(dest string, name string) (string, error) {
+	clean := path.Clean(strings.ReplaceAll(name, "\\", "/"))
+	if path.IsAbs(clean) || filepath.VolumeName(filepath.FromSlash(clean)) != "" ||
+		(len(clean) > 1 && clean[1] == ':') {
+		return "", fmt.Errorf("%s: absolute paths are not allowed", name)
+	}
+	if clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("%s: paths outside of the song folder are not allowed", name)
+	}
+	return filepath.Join(dest, filepath.FromSlash(clean)), nil
+}
+
+// extractFile writes `zf` to `target`, failing if it is larger than MaxFileSize or `remaining`
+func extractFile(zf *zip.File, target string, remaining int64) (n int64, err error) {
+	limit := int64(MaxFileSize)
+	if remaining < limit {
+		limit = remaining
+	}
+	if err = os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return
+	}
+	rc, err := zf.Open()
+	if err != nil {
+		return
+	}
+	defer rc.Close()
+	f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return
+	}
+	n, err = io.Copy(f, io.LimitReader(rc, limit+1))
+	if errC := f.Close(); err == nil {
+		err = errC
+	}
+	if err == nil && n > limit {
+		err = fmt.Errorf("%s is larger than allowed", zf.Name)
+	}
+	if err != nil {
+		err = fmt.Errorf("cannot extract %s: %v", zf.Name, err)
+	}
+	return
+}
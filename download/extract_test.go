@@ -0,0 +1,101 @@
+package download
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cosandr/go-beat-playlist/internal/fsutil"
+)
+
+// makeZIP returns a ZIP file of `files`, keyed by name
+func makeZIP(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, data := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(data))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestExtractZIP(t *testing.T) {
+	dir, err := ioutil.TempDir("", "extract")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	in := makeZIP(t, map[string]string{
+		"Info.dat":            "{}",
+		"Sub/Easy.dat":        "{}",
+		"Sub\\Deeper\\bg.png": "png",
+		"song.EGG":            "OggS",
+		"Empty/":              "",
+		"setup.exe":           "MZ",
+		"script.py":           "print()",
+		"noext":               "x",
+	})
+	song := filepath.Join(dir, "song")
+	if err = ExtractZIP(song, &in); err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+	for _, name := range []string{"Info.dat", "Sub/Easy.dat", "Sub/Deeper/bg.png", "song.EGG"} {
+		if !fsutil.FileExists(filepath.Join(song, name)) {
+			t.Errorf("%s was not extracted", name)
+		}
+	}
+	if !fsutil.DirExists(filepath.Join(song, "Empty")) {
+		t.Error("Empty folder was not created")
+	}
+	// Only song assets are extracted
+	for _, name := range []string{"setup.exe", "script.py", "noext"} {
+		if fsutil.FileExists(filepath.Join(song, name)) {
+			t.Errorf("%s should have been skipped", name)
+		}
+	}
+	if fi, err := os.Stat(filepath.Join(song, "Info.dat")); err == nil && fi.Mode().Perm()&0111 != 0 {
+		t.Errorf("Expected Info.dat not to be executable, got %s", fi.Mode())
+	}
+
+	var bad = map[string]map[string]string{
+		"traversal":      {"Info.dat": "{}", "../evil.dat": "x"},
+		"deep traversal": {"Sub/../../evil.dat": "x"},
+		"backslash":      {"..\\evil.dat": "x"},
+		"absolute":       {"/tmp/evil.dat": "x"},
+		"drive":          {"C:/evil.dat": "x"},
+	}
+	for name, files := range bad {
+		in := makeZIP(t, files)
+		target := filepath.Join(dir, "bad", "song")
+		if err = ExtractZIP(target, &in); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+		if fsutil.FileExists(filepath.Join(dir, "bad", "evil.dat")) || fsutil.FileExists(filepath.Join(target, "Info.dat")) {
+			t.Errorf("%s: files were extracted", name)
+		}
+	}
+
+	// Declared sizes are checked before extracting, the real size while extracting
+	in = makeZIP(t, map[string]string{"big.dat": strings.Repeat("0", MaxFileSize+1)})
+	if err = ExtractZIP(filepath.Join(dir, "big"), &in); err == nil {
+		t.Error("Expected an error for a file larger than MaxFileSize")
+	}
+	zr, err := zip.NewReader(bytes.NewReader(in), int64(len(in)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	zr.File[0].UncompressedSize64 = 1
+	if err = extract(filepath.Join(dir, "lying"), zr); err == nil {
+		t.Error("Expected an error for a file larger than its declared size")
+	}
+}
@@ -0,0 +1,350 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cosandr/go-beat-playlist/internal/fsutil"
+	"github.com/cosandr/go-beat-playlist/internal/web"
+	"github.com/cosandr/go-beat-playlist/playlist"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultWorkers is the number of songs downloaded at the same time by default
+	DefaultWorkers = 4
+	// DefaultHostDelay is the default time between the start of two requests to the same host
+	DefaultHostDelay = 250 * time.Millisecond
+	// SongSpace is the disk space we expect a song to need, its download and extracted files together
+	SongSpace = 30 << 20
+)
+
+// Result is the outcome of downloading one song
+type Result struct {
+	// Song is the installed song on success, the requested one otherwise
+	Song playlist.Song
+	// Playlists are the titles of the playlists which asked for the song, as their files have them once it finished
+	Playlists []string
+	Err       error
+	Elapsed   time.Duration
+}
+
+// Summary is the outcome of a Manager run
+type Summary struct {
+	Succeeded []Result
+	Failed    []Result
+	Elapsed   time.Duration
+}
+
+// String returns the number of downloaded and failed songs, followed by each failure
+func (s *Summary) String() string {
+	ret := fmt.Sprintf("%d downloaded, %d failed in %s\n", len(s.Succeeded), len(s.Failed),
+		s.Elapsed.Round(time.Second))
+	for _, r := range s.Failed {
+		ret += fmt.Sprintf("  %s (%v): %v\n", r.Song.String(), r.Playlists, r.Err)
+	}
+	return ret
+}
+
+// job is a queued song and the paths of the playlists which want it
+type job struct {
+	song      playlist.Song
+	playlists []string
+	// item is the song in the Queue, if the Manager has one
+	item *QueueItem
+}
+
+// Manager downloads a queue of songs with several workers
+//
+// Songs are queued once even if several playlists list them. All workers share a bandwidth limit and space out
+// their requests to the same host.
+type Manager struct {
+	songsDir  string
+	workers   int
+	bandwidth int64
+	hostDelay time.Duration
+	progress  func(r Result, done, total int)
+	queue     *Queue
+	jobs      []*job
+	byHash    map[string]*job
+	byKey     map[string]*job
+	download  func(ctx context.Context, s *playlist.Song, songsDir string) (playlist.Song, error)
+}
+
+// NewManager returns a Manager installing songs in `songsDir`
+func NewManager(songsDir string) *Manager {
+	return &Manager{
+		songsDir:  songsDir,
+		workers:   DefaultWorkers,
+		hostDelay: DefaultHostDelay,
+		byHash:    make(map[string]*job),
+		byKey:     make(map[string]*job),
+		download:  DownloadSongContext,
+	}
+}
+
+// SetWorkers sets the number of songs downloaded at the same time, at least one
+func (m *Manager) SetWorkers(n int) {
+	if n < 1 {
+		n = 1
+	}
+	m.workers = n
+}
+
+// SetBandwidth caps the combined download speed in bytes per second, zero for no limit
+func (m *Manager) SetBandwidth(bytesPerSec int64) {
+	m.bandwidth = bytesPerSec
+}
+
+// SetHostDelay sets the minimum time between the start of two requests to the same host
+func (m *Manager) SetHostDelay(d time.Duration) {
+	m.hostDelay = d
+}
+
+// SetProgress sets a function called after each song, from one goroutine at a time
+func (m *Manager) SetProgress(fn func(r Result, done, total int)) {
+	m.progress = fn
+}
+
+// SetQueue keeps the songs to download in `q` and queues its pending songs, which were left by an earlier run
+//
+// Songs are removed from `q` once downloaded, failures are recorded, and `q` is saved after each song
+func (m *Manager) SetQueue(q *Queue) {
+	m.queue = q
+	for _, it := range q.Items() {
+		if it.Failed {
+			continue
+		}
+		if len(it.Playlists) == 0 {
+			m.Add(it.Song(), "")
+		}
+		for _, p := range it.Playlists {
+			m.Add(it.Song(), p)
+		}
+	}
+}
+
+// Add queues `s` for the playlist file `from`, returns false if it was already queued or cannot be downloaded
+//
+// Songs already queued only remember `from`, official levels are never queued. Songs which failed in the Queue
+// are not downloaded again until they are retried.
+func (m *Manager) Add(s playlist.Song, from string) bool {
+	if s.IsBuiltIn() {
+		return false
+	}
+	var item *QueueItem
+	if m.queue != nil {
+		if item = m.queue.add(&s, from); item.Failed {
+			return false
+		}
+	}
+	if j := m.find(&s); j != nil {
+		// Songs listed by hash in one playlist and by key in another are found by either from now on
+		if j.song.Hash == "" && s.Hash != "" {
+			j.song.Hash = s.Hash
+		}
+		if j.song.Key == "" && s.Key != "" {
+			j.song.Key = s.Key
+		}
+		m.index(j)
+		if from == "" {
+			return false
+		}
+		for _, p := range j.playlists {
+			if p == from {
+				return false
+			}
+		}
+		j.playlists = append(j.playlists, from)
+		return false
+	}
+	j := &job{song: s, item: item}
+	if from != "" {
+		j.playlists = []string{from}
+	}
+	m.jobs = append(m.jobs, j)
+	m.index(j)
+	return true
+}
+
+// index makes `j` found by its hash and key, regardless of case
+func (m *Manager) index(j *job) {
+	if j.song.Hash != "" {
+		m.byHash[strings.ToLower(j.song.Hash)] = j
+	}
+	if j.song.Key != "" {
+		m.byKey[strings.ToLower(j.song.Key)] = j
+	}
+}
+
+// AddMissing queues all songs of `missing`, a map of playlists keyed by path as Library.Missing returns
+func (m *Manager) AddMissing(missing map[string]playlist.Playlist) {
+	// Queue in a stable order, so songs are downloaded in the same order every time
+	paths := make([]string, 0, len(missing))
+	for path := range missing {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		p := missing[path]
+		for _, s := range p.Songs {
+			m.Add(s, path)
+		}
+	}
+}
+
+// Len returns the number of queued songs
+func (m *Manager) Len() int {
+	return len(m.jobs)
+}
+
+// CheckSpace returns an error if the songs folder may not have enough free space for all queued songs
+//
+// Platforms where free space cannot be read are assumed to have enough
+func (m *Manager) CheckSpace() error {
+	free, err := fsutil.FreeSpace(m.songsDir)
+	if err != nil {
+		log.Debugf("Cannot check free space in %s: %v", m.songsDir, err)
+		return nil
+	}
+	if need := uint64(len(m.jobs)) * SongSpace; free < need {
+		return fmt.Errorf("%d songs need about %d MiB, only %d MiB free in %s", len(m.jobs), need>>20, free>>20,
+			m.songsDir)
+	}
+	return nil
+}
+
+// find returns the queued job of `s`, matching by hash and then key regardless of case, the same as the Queue
+func (m *Manager) find(s *playlist.Song) *job {
+	if j, ok := m.byHash[strings.ToLower(s.Hash)]; ok && s.Hash != "" {
+		return j
+	}
+	if j, ok := m.byKey[strings.ToLower(s.Key)]; ok && s.Key != "" {
+		return j
+	}
+	return nil
+}
+
+// Run downloads all queued songs and empties the queue, songs not started when `ctx` is done fail with its error
+//
+// Leftovers of interrupted runs are removed from the staging folder first, only one Manager may run in a songs
+// folder at a time
+func (m *Manager) Run(ctx context.Context) (sum Summary) {
+	start := time.Now()
+	cleanStaging(m.songsDir)
+	jobs := m.jobs
+	m.jobs = nil
+	m.byHash = make(map[string]*job)
+	m.byKey = make(map[string]*job)
+
+	if m.hostDelay > 0 {
+		ctx = web.WithHostLimiter(ctx, web.NewHostLimiter(m.hostDelay))
+	}
+	if m.bandwidth > 0 {
+		ctx = web.WithRateLimiter(ctx, web.NewRateLimiter(m.bandwidth))
+	}
+	m.saveQueue()
+	queue := make(chan int)
+	results := make([]Result, len(jobs))
+	titles := make(playlistTitles)
+	var mu sync.Mutex
+	var done int
+	var wg sync.WaitGroup
+	for w := 0; w < m.workers && w < len(jobs); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				r := m.run(ctx, jobs[i])
+				mu.Lock()
+				r.Playlists = titles.get(r.Playlists)
+				results[i] = r
+				if m.queue != nil && jobs[i].item != nil {
+					// Songs cancelled before they finished are resumed as if they were never started
+					if r.Err == nil {
+						m.queue.done(jobs[i].item)
+					} else if !errors.Is(r.Err, context.Canceled) {
+						m.queue.fail(jobs[i].item, r.Err)
+					}
+					m.saveQueue()
+				}
+				done++
+				if m.progress != nil {
+					m.progress(r, done, len(jobs))
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := range jobs {
+		queue <- i
+	}
+	close(queue)
+	wg.Wait()
+	for _, r := range results {
+		if r.Err != nil {
+			sum.Failed = append(sum.Failed, r)
+		} else {
+			sum.Succeeded = append(sum.Succeeded, r)
+		}
+	}
+	sum.Elapsed = time.Since(start)
+	return
+}
+
+// playlistTitles are the titles of playlist files, read once each
+type playlistTitles map[string]string
+
+// get returns the titles of the playlist files `paths`
+//
+// Playlists are read when first needed, so titles changed since songs were queued are shown. Files which cannot be
+// read, such as playlists deleted since, are named by their file name.
+func (t playlistTitles) get(paths []string) []string {
+	var ret []string
+	for _, path := range paths {
+		title, ok := t[path]
+		if !ok {
+			if p, err := playlist.MakePlaylist(path); err == nil && p.Title != "" {
+				title = p.Title
+			} else {
+				title = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+			}
+			t[path] = title
+		}
+		ret = append(ret, title)
+	}
+	return ret
+}
+
+// saveQueue saves the Queue, if there is one
+func (m *Manager) saveQueue() {
+	if m.queue == nil {
+		return
+	}
+	if err := m.queue.Save(); err != nil {
+		log.Warnf("Cannot save download queue: %v", err)
+	}
+}
+
+// run downloads the song of `j`
+func (m *Manager) run(ctx context.Context, j *job) Result {
+	start := time.Now()
+	r := Result{Song: j.song, Playlists: j.playlists}
+	if r.Err = ctx.Err(); r.Err != nil {
+		return r
+	}
+	installed, err := m.download(ctx, &j.song, m.songsDir)
+	if err != nil {
+		r.Err = err
+	} else {
+		r.Song = installed
+	}
+	r.Elapsed = time.Since(start)
+	return r
+}
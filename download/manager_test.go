@@ -0,0 +1,140 @@
+package download
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cosandr/go-beat-playlist/internal/web"
+	"github.com/cosandr/go-beat-playlist/playlist"
+)
+
+func TestManager(t *testing.T) {
+	m := NewManager(t.Name())
+	m.SetWorkers(3)
+	m.SetHostDelay(0)
+	var mu sync.Mutex
+	var running, maxRunning int
+	downloaded := make(map[string]int)
+	m.download = func(ctx context.Context, s *playlist.Song, songsDir string) (playlist.Song, error) {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		downloaded[s.Hash+s.Key]++
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		if s.Hash == "bad" {
+			return playlist.Song{}, fmt.Errorf("not found")
+		}
+		return *s, nil
+	}
+	// The playlist files do not exist, they are named by their file name
+	missing := map[string]playlist.Playlist{
+		"a.bplist": {Title: "A", Songs: []playlist.Song{{Hash: "1"}, {Hash: "2"}, {Hash: "bad"}, {LevelID: "100Bills"}, {Key: "1A2B"}}},
+		// Hashes and keys are compared regardless of case
+		"b.bplist": {Title: "B", Songs: []playlist.Song{{Hash: "2"}, {Hash: "3"}, {Hash: "4"}, {Hash: "5"}, {Key: "1a2b"}}},
+	}
+	m.AddMissing(missing)
+	if m.Len() != 7 {
+		t.Fatalf("Expected 7 queued songs, got %d", m.Len())
+	}
+	var calls int
+	m.SetProgress(func(r Result, done int, total int) {
+		calls++
+		if total != 7 || done != calls {
+			t.Errorf("Unexpected progress %d/%d on call %d", done, total, calls)
+		}
+	})
+	sum := m.Run(context.Background())
+	if len(sum.Succeeded) != 6 || len(sum.Failed) != 1 {
+		t.Errorf("Expected 6 downloaded and 1 failed, got\n%s", sum.String())
+	}
+	if f := sum.Failed; len(f) == 1 && (f[0].Song.Hash != "bad" || f[0].Playlists[0] != "a") {
+		t.Errorf("Unexpected failure %+v", f[0])
+	}
+	for hash, n := range downloaded {
+		if n != 1 {
+			t.Errorf("Expected %s to be downloaded once, got %d", hash, n)
+		}
+	}
+	if maxRunning > 3 || maxRunning < 2 {
+		t.Errorf("Expected up to 3 downloads at once, got %d", maxRunning)
+	}
+	if m.Len() != 0 {
+		t.Errorf("Expected queue to be empty after run, got %d", m.Len())
+	}
+	// Songs not started when cancelled fail
+	m.SetProgress(nil)
+	m.AddMissing(missing)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if sum = m.Run(ctx); len(sum.Failed) != 7 {
+		t.Errorf("Expected all songs to fail after cancel, got\n%s", sum.String())
+	}
+}
+
+func TestManagerKeyAndHash(t *testing.T) {
+	m := NewManager(t.Name())
+	// The second song links the key of the first to a hash, the third is only listed by that hash
+	songs := []playlist.Song{{Key: "1a2b"}, {Key: "1A2B", Hash: "abc"}, {Hash: "ABC"}, {Hash: "def", Key: "3c4d"}, {Key: "3C4D"}}
+	for i, s := range songs {
+		m.Add(s, fmt.Sprintf("P%d", i))
+	}
+	if m.Len() != 2 {
+		t.Fatalf("Expected 2 queued songs, got %d", m.Len())
+	}
+	j := m.jobs[0]
+	if j.song.Hash != "abc" || j.song.Key != "1a2b" || len(j.playlists) != 3 {
+		t.Errorf("Expected song abc with key 1a2b for 3 playlists, got %+v for %v", j.song, j.playlists)
+	}
+	if m.find(&playlist.Song{Hash: "Abc"}) != j || m.find(&playlist.Song{Key: "1A2b"}) != j {
+		t.Error("Expected song to be found by hash and key")
+	}
+}
+
+func TestManagerPlaylistTitles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "songs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	q, err := OpenQueue(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewManager(dir)
+	m.SetHostDelay(0)
+	m.download = func(ctx context.Context, s *playlist.Song, songsDir string) (playlist.Song, error) {
+		return *s, &web.StatusError{StatusCode: 404, Status: "404 Not Found"}
+	}
+	m.SetQueue(q)
+	renamed := filepath.Join(dir, "renamed.bplist")
+	gone := filepath.Join(dir, "gone.bplist")
+	m.AddMissing(map[string]playlist.Playlist{
+		renamed: {Title: "Old", Songs: []playlist.Song{{Hash: "abc"}}},
+		gone:    {Title: "Gone", Songs: []playlist.Song{{Hash: "abc"}}},
+	})
+	// The playlist is renamed after the song was queued
+	if err = ioutil.WriteFile(renamed, []byte(`{"playlistTitle": "New", "songs": []}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sum := m.Run(context.Background())
+	if len(sum.Failed) != 1 || !reflect.DeepEqual(sum.Failed[0].Playlists, []string{"gone", "New"}) {
+		t.Errorf("Expected the failure to list playlists gone and New, got\n%s", sum.String())
+	}
+	// The queue keeps the playlist files
+	if it := q.Items(); len(it) != 1 || !reflect.DeepEqual(it[0].Playlists, []string{gone, renamed}) {
+		t.Errorf("Expected the queue to keep playlist paths, got %v", it)
+	}
+}
@@ -0,0 +1,263 @@
+package download
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cosandr/go-beat-playlist/internal/web"
+	"github.com/cosandr/go-beat-playlist/playlist"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// queueName is the file name of the download queue in the staging folder
+	queueName = "queue.json"
+	// badSuffix is added to queue files we cannot read, they are kept for the user to look at
+	badSuffix = ".bad"
+	// queueVersion is bumped whenever the queue file changes in a way older versions cannot read
+	queueVersion = 1
+	// MaxAttempts is how many runs may fail to download a song before it is marked as failed
+	MaxAttempts = 3
+)
+
+// QueueItem is a song waiting to be downloaded
+type QueueItem struct {
+	Hash string `json:"hash,omitempty"`
+	Key  string `json:"key,omitempty"`
+	Name string `json:"name,omitempty"`
+	// Playlists are the paths of the playlist files which want the song
+	Playlists []string  `json:"playlists,omitempty"`
+	Added     time.Time `json:"added"`
+	// Attempts is the number of runs which failed to download the song, LastError the error of the last one
+	Attempts  int    `json:"attempts"`
+	LastError string `json:"lastError,omitempty"`
+	// Failed items are not resumed until they are retried
+	Failed bool `json:"failed,omitempty"`
+}
+
+// Song returns the song to download
+func (it *QueueItem) Song() playlist.Song {
+	return playlist.Song{Hash: it.Hash, Key: it.Key, Name: it.Name}
+}
+
+// Matches returns true if `id` is the hash or key of this item
+func (it *QueueItem) Matches(id string) bool {
+	return (it.Hash != "" && strings.EqualFold(it.Hash, id)) || (it.Key != "" && strings.EqualFold(it.Key, id))
+}
+
+// String returns the song, its state and its playlists
+func (it *QueueItem) String() string {
+	s := it.Song()
+	ret := s.String()
+	if it.Hash != "" {
+		ret += " " + it.Hash
+	}
+	state := "pending"
+	if it.Failed {
+		state = "failed"
+	}
+	ret += fmt.Sprintf(" (%s, %d attempts)", state, it.Attempts)
+	if len(it.Playlists) > 0 {
+		ret += fmt.Sprintf(" %v", it.Playlists)
+	}
+	if it.LastError != "" {
+		ret += ": " + it.LastError
+	}
+	return ret
+}
+
+// queueFile is the structure of the queue file
+type queueFile struct {
+	Version int          `json:"version"`
+	Items   []*QueueItem `json:"items"`
+}
+
+// Queue is the list of songs to download, kept on disk so an interrupted download can be resumed
+//
+// It is not safe for concurrent use, Manager serializes its changes
+type Queue struct {
+	path  string
+	items []*QueueItem
+}
+
+// QueuePath returns the path of the download queue of `songsDir`
+func QueuePath(songsDir string) string {
+	return filepath.Join(songsDir, StagingDir, queueName)
+}
+
+// OpenQueue reads the download queue of `songsDir`, it is empty if there is none
+//
+// A queue file we cannot read, such as one written by an older version, is moved aside to queue.json.bad with a
+// warning and the queue starts empty. Queues written by a newer version are an error, they are never replaced.
+func OpenQueue(songsDir string) (q *Queue, err error) {
+	q = &Queue{path: QueuePath(songsDir)}
+	file, err := ioutil.ReadFile(q.path)
+	if os.IsNotExist(err) {
+		return q, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var qf queueFile
+	var reason string
+	if err = json.Unmarshal(file, &qf); err != nil {
+		reason = fmt.Sprintf("cannot parse it: %v", err)
+	} else if qf.Version > queueVersion {
+		return nil, fmt.Errorf("%s was written by a newer version (%d, expected %d), not replacing it", q.path,
+			qf.Version, queueVersion)
+	} else if qf.Version != queueVersion {
+		reason = fmt.Sprintf("it has version %d, expected %d", qf.Version, queueVersion)
+	} else {
+		q.items = qf.Items
+		return q, nil
+	}
+	bad := q.path + badSuffix
+	if err = os.Rename(q.path, bad); err != nil {
+		return nil, err
+	}
+	log.Warnf("Ignoring download queue %s, %s, it was moved to %s", q.path, reason, bad)
+	return q, nil
+}
+
+// cleanStaging removes everything but the queue and unreadable queues from the staging folder of `songsDir`
+//
+// Interrupted downloads are only ever found in the staging folder, the queue remembers their songs
+func cleanStaging(songsDir string) {
+	staging := filepath.Join(songsDir, StagingDir)
+	entries, err := ioutil.ReadDir(staging)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Warnf("Cannot read %s: %v", staging, err)
+		}
+		return
+	}
+	for _, e := range entries {
+		if e.Name() == queueName || e.Name() == queueName+badSuffix {
+			continue
+		}
+		log.Debugf("Removing interrupted download %s", e.Name())
+		if err = os.RemoveAll(filepath.Join(staging, e.Name())); err != nil {
+			log.Warnf("Cannot remove interrupted download: %v", err)
+		}
+	}
+}
+
+// Save writes the queue, the file is removed once the queue is empty
+func (q *Queue) Save() error {
+	if len(q.items) == 0 {
+		if err := os.Remove(q.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	file, err := json.MarshalIndent(queueFile{Version: queueVersion, Items: q.items}, "", " ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(q.path), 0755); err != nil {
+		return err
+	}
+	// Write to a temporary file first, an interrupted write must not lose the queue
+	tmp := q.path + ".tmp"
+	if err = ioutil.WriteFile(tmp, file, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, q.path)
+}
+
+// Items returns all queued songs, in the order they were added
+func (q *Queue) Items() []*QueueItem {
+	return q.items
+}
+
+// Len returns the number of queued songs, failed ones included
+func (q *Queue) Len() int {
+	return len(q.items)
+}
+
+// Pending returns the number of songs which are not failed
+func (q *Queue) Pending() (n int) {
+	for _, it := range q.items {
+		if !it.Failed {
+			n++
+		}
+	}
+	return
+}
+
+// Retry marks failed items for which `fn` returns true as pending again, returns how many were
+func (q *Queue) Retry(fn func(it *QueueItem) bool) (n int) {
+	for _, it := range q.items {
+		if it.Failed && fn(it) {
+			it.Failed = false
+			it.Attempts = 0
+			n++
+		}
+	}
+	return
+}
+
+// Remove removes items for which `fn` returns true, returns how many were
+func (q *Queue) Remove(fn func(it *QueueItem) bool) (n int) {
+	kept := q.items[:0]
+	for _, it := range q.items {
+		if fn(it) {
+			n++
+		} else {
+			kept = append(kept, it)
+		}
+	}
+	q.items = kept
+	return
+}
+
+// add queues `s` for the playlist file `from` and returns its item, songs already queued only remember `from`
+func (q *Queue) add(s *playlist.Song, from string) *QueueItem {
+	var it *QueueItem
+	for _, found := range q.items {
+		if (s.Hash != "" && found.Matches(s.Hash)) || (s.Key != "" && found.Matches(s.Key)) {
+			it = found
+			break
+		}
+	}
+	if it == nil {
+		it = &QueueItem{Hash: s.Hash, Key: s.Key, Name: s.Name, Added: time.Now()}
+		q.items = append(q.items, it)
+	}
+	if it.Hash == "" {
+		it.Hash = s.Hash
+	}
+	if it.Key == "" {
+		it.Key = s.Key
+	}
+	if from == "" {
+		return it
+	}
+	for _, p := range it.Playlists {
+		if p == from {
+			return it
+		}
+	}
+	it.Playlists = append(it.Playlists, from)
+	return it
+}
+
+// done removes `it` once its song is installed
+func (q *Queue) done(it *QueueItem) {
+	q.Remove(func(found *QueueItem) bool { return found == it })
+}
+
+// fail records that downloading `it` failed with `err`
+//
+// Items fail for good after MaxAttempts, or right away if `err` is not worth retrying
+func (q *Queue) fail(it *QueueItem, err error) {
+	it.Attempts++
+	it.LastError = err.Error()
+	if it.Attempts >= MaxAttempts || !web.Retryable(err) {
+		it.Failed = true
+	}
+}
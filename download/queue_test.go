@@ -0,0 +1,220 @@
+package download
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cosandr/go-beat-playlist/internal/fsutil"
+	"github.com/cosandr/go-beat-playlist/internal/web"
+	"github.com/cosandr/go-beat-playlist/playlist"
+)
+
+func TestQueue(t *testing.T) {
+	dir, err := ioutil.TempDir("", "songs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// Leftovers of an interrupted download
+	leftover := filepath.Join(dir, StagingDir, "song-123")
+	if err = os.MkdirAll(leftover, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	errs := map[string]error{
+		"busy":    &web.StatusError{StatusCode: 503, Status: "503 Service Unavailable"},
+		"deleted": &web.StatusError{StatusCode: 404, Status: "404 Not Found"},
+	}
+	newManager := func() (*Manager, *Queue) {
+		q, err := OpenQueue(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := NewManager(dir)
+		m.SetHostDelay(0)
+		m.download = func(ctx context.Context, s *playlist.Song, songsDir string) (playlist.Song, error) {
+			return *s, errs[s.Hash]
+		}
+		m.SetQueue(q)
+		return m, q
+	}
+
+	m, q := newManager()
+	m.AddMissing(map[string]playlist.Playlist{
+		"a.bplist": {Title: "A", Songs: []playlist.Song{{Hash: "ok"}, {Hash: "busy"}, {Hash: "deleted"}}},
+		"b.bplist": {Title: "B", Songs: []playlist.Song{{Hash: "busy"}}},
+	})
+	m.Run(context.Background())
+	if fsutil.DirExists(leftover) {
+		t.Error("Interrupted download was not removed")
+	}
+	if !fsutil.FileExists(QueuePath(dir)) {
+		t.Fatal("Queue was not saved")
+	}
+
+	// The retryable failure is resumed by the next run, the permanent one is not
+	m, q = newManager()
+	if q.Len() != 2 || q.Pending() != 1 || m.Len() != 1 {
+		t.Fatalf("Expected 2 songs in the queue and 1 resumed, got %d, %d and %d", q.Len(), q.Pending(), m.Len())
+	}
+	for _, it := range q.Items() {
+		switch it.Hash {
+		case "busy":
+			if it.Failed || it.Attempts != 1 || len(it.Playlists) != 2 || it.LastError == "" {
+				t.Errorf("Unexpected item %s", it.String())
+			}
+		case "deleted":
+			if !it.Failed || it.Attempts != 1 {
+				t.Errorf("Unexpected item %s", it.String())
+			}
+		default:
+			t.Errorf("Unexpected item %s", it.String())
+		}
+	}
+	// Failed songs are not downloaded again, even if they are still missing
+	if m.Add(playlist.Song{Hash: "deleted"}, "C") {
+		t.Error("Failed song was queued again")
+	}
+	for i := 1; i < MaxAttempts; i++ {
+		m.Run(context.Background())
+		m.SetQueue(q)
+	}
+	if q.Pending() != 0 {
+		t.Errorf("Expected all songs to fail after %d attempts, %d are pending", MaxAttempts, q.Pending())
+	}
+
+	// Cancelled runs do not count as attempts
+	if n := q.Retry(func(it *QueueItem) bool { return it.Matches("BUSY") }); n != 1 {
+		t.Errorf("Expected 1 song to be retried, got %d", n)
+	}
+	m.SetQueue(q)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	m.Run(ctx)
+	if _, q = newManager(); q.Pending() != 1 || q.Items()[0].Attempts != 0 {
+		t.Errorf("Expected a pending song without attempts, got %v", q.Items())
+	}
+	if n := q.Remove(func(*QueueItem) bool { return true }); n != 2 {
+		t.Errorf("Expected 2 songs to be removed, got %d", n)
+	}
+	if err = q.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if fsutil.FileExists(QueuePath(dir)) {
+		t.Error("Empty queue was not removed")
+	}
+}
+
+func TestQueueFailureOnCancel(t *testing.T) {
+	dir, err := ioutil.TempDir("", "songs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	q, err := OpenQueue(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m := NewManager(dir)
+	m.SetHostDelay(0)
+	// The song fails for another reason just as the run is cancelled
+	m.download = func(ctx context.Context, s *playlist.Song, songsDir string) (playlist.Song, error) {
+		cancel()
+		return *s, &web.StatusError{StatusCode: 503, Status: "503 Service Unavailable"}
+	}
+	m.SetQueue(q)
+	m.Add(playlist.Song{Hash: "busy"}, "A")
+	m.Run(ctx)
+	if it := q.Items(); len(it) != 1 || it[0].Attempts != 1 || it[0].LastError == "" {
+		t.Errorf("Expected the failure to be recorded, got %v", it)
+	}
+}
+
+func TestCleanStaging(t *testing.T) {
+	dir, err := ioutil.TempDir("", "songs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	q, err := OpenQueue(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.add(&playlist.Song{Hash: "abc"}, "A")
+	if err = q.Save(); err != nil {
+		t.Fatal(err)
+	}
+	staging := filepath.Join(dir, StagingDir)
+	if err = os.MkdirAll(filepath.Join(staging, "song-123"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(filepath.Join(staging, "456.zip"), []byte("partial"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cleanStaging(dir)
+	entries, err := ioutil.ReadDir(staging)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != queueName {
+		t.Errorf("Expected only the queue to be left in staging, got %v", entries)
+	}
+	if q, err = OpenQueue(dir); err != nil || q.Len() != 1 {
+		t.Errorf("Expected the queue to be kept, got %v, %v", q, err)
+	}
+}
+
+func TestQueueUnknownVersion(t *testing.T) {
+	dir, err := ioutil.TempDir("", "songs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err = os.MkdirAll(filepath.Join(dir, StagingDir), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// Queues we cannot read are moved aside and must not block downloads
+	for _, data := range []string{`{"version": 0, "items": [{"hash": "abc"}]}`, `{"version": `} {
+		if err = ioutil.WriteFile(QueuePath(dir), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		q, err := OpenQueue(dir)
+		if err != nil {
+			t.Fatalf("%s: expected an empty queue, got %v", data, err)
+		}
+		if q.Len() != 0 {
+			t.Errorf("%s: expected an empty queue, got %v", data, q.Items())
+		}
+		if bad, err := ioutil.ReadFile(QueuePath(dir) + badSuffix); err != nil || string(bad) != data {
+			t.Errorf("%s: expected the queue to be moved aside, got %s, %v", data, bad, err)
+		}
+		q.add(&playlist.Song{Hash: "def"}, "A")
+		if err = q.Save(); err != nil {
+			t.Fatal(err)
+		}
+		if q, err = OpenQueue(dir); err != nil || q.Len() != 1 || q.Items()[0].Hash != "def" {
+			t.Errorf("%s: expected the queue to be replaced, got %v, %v", data, q, err)
+		}
+	}
+	cleanStaging(dir)
+	if _, err = os.Stat(QueuePath(dir) + badSuffix); err != nil {
+		t.Errorf("Expected the unreadable queue to be kept, got %v", err)
+	}
+	// Queues of newer versions are never replaced
+	newer := []byte(`{"version": 99, "items": [{"hash": "abc"}]}`)
+	if err = ioutil.WriteFile(QueuePath(dir), newer, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = OpenQueue(dir); err == nil {
+		t.Error("Expected an error for a queue of a newer version")
+	}
+	if data, err := ioutil.ReadFile(QueuePath(dir)); err != nil || !bytes.Equal(data, newer) {
+		t.Errorf("Expected the newer queue to be left alone, got %s, %v", data, err)
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !windows
+// +build !darwin,!dragonfly,!freebsd,!linux,!windows
+
+package fsutil
+
+import "fmt"
+
+// FreeSpace is not available on this platform
+func FreeSpace(path string) (uint64, error) {
+	return 0, fmt.Errorf("free space cannot be checked on this platform")
+}
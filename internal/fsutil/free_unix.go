@@ -0,0 +1,15 @@
+//go:build darwin || dragonfly || freebsd || linux
+// +build darwin dragonfly freebsd linux
+
+package fsutil
+
+import "golang.org/x/sys/unix"
+
+// FreeSpace returns the number of bytes available to us on the file system of `path`
+func FreeSpace(path string) (uint64, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return 0, err
+	}
+	return uint64(st.Bavail) * uint64(st.Bsize), nil
+}
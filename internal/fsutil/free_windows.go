@@ -0,0 +1,13 @@
+package fsutil
+
+import "golang.org/x/sys/windows"
+
+// FreeSpace returns the number of bytes available to us on the drive of `path`
+func FreeSpace(path string) (free uint64, err error) {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return
+	}
+	err = windows.GetDiskFreeSpaceEx(p, &free, nil, nil)
+	return
+}
@@ -0,0 +1,22 @@
+// Package fsutil holds small file system helpers shared by all packages
+package fsutil
+
+import "os"
+
+// FileExists returns true if `path` exists and is a file
+func FileExists(path string) bool {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return false
+	}
+	return !info.IsDir()
+}
+
+// DirExists returns true if `path` exists and is a directory
+func DirExists(path string) bool {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return false
+	}
+	return info.IsDir()
+}
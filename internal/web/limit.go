@@ -0,0 +1,136 @@
+package web
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// contextKey is the type of the context keys of this package
+type contextKey int
+
+const (
+	hostLimiterKey contextKey = iota
+	rateLimiterKey
+	retryPolicyKey
+)
+
+// HostLimiter spaces out requests to the same host, so bulk downloads stay polite
+type HostLimiter struct {
+	interval time.Duration
+	mu       sync.Mutex
+	next     map[string]time.Time
+}
+
+// NewHostLimiter returns a HostLimiter starting requests to each host at least `interval` apart
+func NewHostLimiter(interval time.Duration) *HostLimiter {
+	return &HostLimiter{interval: interval, next: make(map[string]time.Time)}
+}
+
+// Wait blocks until a request to `host` may start, or until `ctx` is done
+func (h *HostLimiter) Wait(ctx context.Context, host string) error {
+	h.mu.Lock()
+	now := time.Now()
+	start := h.next[host]
+	if start.Before(now) {
+		start = now
+	}
+	// Reserve the slot before waiting, other requests queue up behind it
+	h.next[host] = start.Add(h.interval)
+	h.mu.Unlock()
+	return sleep(ctx, start.Sub(now))
+}
+
+// Delay makes requests to `host` wait at least `d` from now, like a server asking us to slow down
+func (h *HostLimiter) Delay(host string, d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if until := time.Now().Add(d); until.After(h.next[host]) {
+		h.next[host] = until
+	}
+}
+
+// RateLimiter caps the combined speed of all response bodies read through it
+type RateLimiter struct {
+	bytesPerSec int64
+	mu          sync.Mutex
+	// next is when the bytes read so far are paid for
+	next time.Time
+}
+
+// NewRateLimiter returns a RateLimiter allowing `bytesPerSec` bytes per second
+func NewRateLimiter(bytesPerSec int64) *RateLimiter {
+	return &RateLimiter{bytesPerSec: bytesPerSec}
+}
+
+// Wait blocks until `n` more bytes may be read, or until `ctx` is done
+func (r *RateLimiter) Wait(ctx context.Context, n int) error {
+	r.mu.Lock()
+	now := time.Now()
+	if r.next.Before(now) {
+		r.next = now
+	}
+	r.next = r.next.Add(time.Duration(int64(n) * int64(time.Second) / r.bytesPerSec))
+	wait := r.next.Sub(now)
+	r.mu.Unlock()
+	return sleep(ctx, wait)
+}
+
+// Reader returns `rd` limited by `r`
+func (r *RateLimiter) Reader(ctx context.Context, rd io.Reader) io.Reader {
+	return &limitedReader{ctx: ctx, limiter: r, r: rd}
+}
+
+// limitedReader reads at most as fast as its limiter allows
+type limitedReader struct {
+	ctx     context.Context
+	limiter *RateLimiter
+	r       io.Reader
+}
+
+// Read reads into `p` and waits until what was read is allowed
+func (l *limitedReader) Read(p []byte) (n int, err error) {
+	// Small reads keep the speed even across several readers
+	if max := int(l.limiter.bytesPerSec / 10); max > 0 && len(p) > max {
+		p = p[:max]
+	}
+	n, err = l.r.Read(p)
+	if n > 0 {
+		if errW := l.limiter.Wait(l.ctx, n); errW != nil {
+			return n, errW
+		}
+	}
+	return
+}
+
+// limitedBody is a response body read through a RateLimiter
+type limitedBody struct {
+	io.Reader
+	io.Closer
+}
+
+// WithHostLimiter returns a context whose requests are spaced out by `h`
+func WithHostLimiter(ctx context.Context, h *HostLimiter) context.Context {
+	return context.WithValue(ctx, hostLimiterKey, h)
+}
+
+// WithRateLimiter returns a context whose response bodies are read no faster than `r` allows
+func WithRateLimiter(ctx context.Context, r *RateLimiter) context.Context {
+	return context.WithValue(ctx, rateLimiterKey, r)
+}
+
+// sleep waits for `d` or until `ctx` is done
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,52 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func TestHostLimiter(t *testing.T) {
+	h := NewHostLimiter(50 * time.Millisecond)
+	ctx := context.Background()
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := h.Wait(ctx, "example.com"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("Expected requests to be spaced out, 3 took %s", elapsed)
+	}
+	// Other hosts are not delayed
+	start = time.Now()
+	if err := h.Wait(ctx, "example.org"); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 20*time.Millisecond {
+		t.Errorf("Expected no delay for another host, got %s", elapsed)
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	r := NewRateLimiter(100 * 1024)
+	data := make([]byte, 30*1024)
+	start := time.Now()
+	out, err := ioutil.ReadAll(r.Reader(context.Background(), bytes.NewReader(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != len(data) {
+		t.Errorf("Expected %d bytes, got %d", len(data), len(out))
+	}
+	if elapsed := time.Since(start); elapsed < 250*time.Millisecond {
+		t.Errorf("Expected 30KB at 100KB/s to take 300ms, took %s", elapsed)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err = ioutil.ReadAll(r.Reader(ctx, bytes.NewReader(data))); err == nil {
+		t.Error("Expected an error after cancel")
+	}
+}
@@ -0,0 +1,175 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// RetryPolicy is how failed requests are retried
+type RetryPolicy struct {
+	// Attempts is the total number of attempts, at least one
+	Attempts int
+	// MinDelay is the delay before the first retry, it doubles with each retry up to MaxDelay
+	MinDelay time.Duration
+	MaxDelay time.Duration
+	// MaxRetryAfter is the longest Retry-After we wait for, requests asked to wait longer fail. Zero waits for any.
+	MaxRetryAfter time.Duration
+	// Timeout is how long an attempt waits for the response, and for more of the body once it started
+	Timeout time.Duration
+}
+
+// DefaultRetry is the RetryPolicy of requests whose context has none
+var DefaultRetry = RetryPolicy{
+	Attempts:      4,
+	MinDelay:      time.Second,
+	MaxDelay:      30 * time.Second,
+	MaxRetryAfter: 2 * time.Minute,
+	Timeout:       30 * time.Second,
+}
+
+// StatusError is returned for responses without a 2xx status
+type StatusError struct {
+	URL        string
+	StatusCode int
+	Status     string
+	// RetryAfter is how long the server asked us to wait, zero if it did not
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("HTTP GET %s failed: %s", e.URL, e.Status)
+}
+
+// TimeoutError is returned when a response or its body does not arrive in time
+type TimeoutError struct {
+	URL     string
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("HTTP GET %s timed out after %s", e.URL, e.Timeout)
+}
+
+// Retryable returns true if the request which failed with `err` may succeed when sent again
+//
+// Server errors, rate limiting, timeouts and dropped connections are retryable. Other statuses, unknown hosts,
+// cancelled contexts and anything else are permanent.
+func Retryable(err error) bool {
+	var statusErr *StatusError
+	var timeoutErr *TimeoutError
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	switch {
+	case err == nil:
+		return false
+	case errors.As(err, &statusErr):
+		switch statusErr.StatusCode {
+		case http.StatusRequestTimeout, http.StatusTooManyRequests:
+			return true
+		case http.StatusNotImplemented, http.StatusHTTPVersionNotSupported:
+			return false
+		}
+		return statusErr.StatusCode >= 500
+	case errors.As(err, &timeoutErr):
+		return true
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return false
+	case errors.As(err, &dnsErr):
+		return !dnsErr.IsNotFound
+	case errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, io.EOF),
+		errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.ECONNREFUSED):
+		return true
+	case errors.As(err, &netErr):
+		return netErr.Timeout()
+	}
+	return false
+}
+
+// WithRetryPolicy returns a context whose requests are retried following `p`
+func WithRetryPolicy(ctx context.Context, p RetryPolicy) context.Context {
+	return context.WithValue(ctx, retryPolicyKey, p)
+}
+
+// retryPolicy returns the RetryPolicy of `ctx`
+func retryPolicy(ctx context.Context) RetryPolicy {
+	if p, ok := ctx.Value(retryPolicyKey).(RetryPolicy); ok {
+		return p
+	}
+	return DefaultRetry
+}
+
+// random is the source of jitter, requests retrying at the same time spread out
+var random = struct {
+	sync.Mutex
+	*rand.Rand
+}{Rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+
+// backoff returns the delay before retry number `retry`, counting from zero
+//
+// The delay is between half and all of MinDelay doubled `retry` times, at most MaxDelay
+func (p *RetryPolicy) backoff(retry int) time.Duration {
+	d := p.MinDelay
+	for i := 0; i < retry && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	if d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if d <= 1 {
+		return d
+	}
+	random.Lock()
+	defer random.Unlock()
+	return d/2 + time.Duration(random.Int63n(int64(d/2)))
+}
+
+// retry calls `fn` until it succeeds, fails with an error which is not Retryable, or runs out of attempts
+func retry(ctx context.Context, url string, fn func() error) (err error) {
+	p := retryPolicy(ctx)
+	for attempt := 1; ; attempt++ {
+		err = fn()
+		if err == nil || attempt >= p.Attempts || !Retryable(err) || ctx.Err() != nil {
+			return
+		}
+		wait := p.backoff(attempt - 1)
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && statusErr.RetryAfter > 0 {
+			if p.MaxRetryAfter > 0 && statusErr.RetryAfter > p.MaxRetryAfter {
+				return fmt.Errorf("%w, asked to retry after %s", err, statusErr.RetryAfter)
+			}
+			wait = statusErr.RetryAfter
+		}
+		log.Debugf("GET %s attempt %d/%d failed, retrying in %s: %v", url, attempt, p.Attempts,
+			wait.Round(time.Millisecond), err)
+		if errS := sleep(ctx, wait); errS != nil {
+			return
+		}
+	}
+}
+
+// parseRetryAfter returns the wait asked for by the Retry-After header `value`, in seconds or as a date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
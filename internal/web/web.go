@@ -0,0 +1,186 @@
+// Package web holds the HTTP client shared by the API clients and the downloader
+package web
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// The user agent used for HTTP GET requests
+const userAgent = "go_beat_playlist/1.0"
+
+// httpClient is shared by all requests, timeouts of whole requests come from their RetryPolicy
+var httpClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   10 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   16,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	},
+}
+
+// Get sends a GET request for `url` with our user agent
+func Get(url string) (resp *http.Response, err error) {
+	return GetContext(context.Background(), url)
+}
+
+// GetContext sends a GET request for `url` with our user agent, it is cancelled when `ctx` is done
+//
+// Failed requests and responses with a retryable status are retried following the RetryPolicy of `ctx`, other
+// responses without a 2xx status return a StatusError. Limiters added to `ctx` with WithHostLimiter and
+// WithRateLimiter are applied.
+func GetContext(ctx context.Context, url string) (resp *http.Response, err error) {
+	err = retry(ctx, url, func() (errDo error) {
+		resp, errDo = do(ctx, url)
+		return
+	})
+	return
+}
+
+// Fetch returns the body of `url`, requests are retried like GetContext, also when reading the body fails
+func Fetch(ctx context.Context, url string) (body []byte, err error) {
+	err = retry(ctx, url, func() error {
+		resp, errDo := do(ctx, url)
+		if errDo != nil {
+			return errDo
+		}
+		defer resp.Body.Close()
+		body, errDo = ioutil.ReadAll(resp.Body)
+		return errDo
+	})
+	return
+}
+
+// FetchFile writes the body of `url` to `f`, requests are retried like Fetch
+//
+// `f` is emptied before each attempt, returns the number of bytes written
+func FetchFile(ctx context.Context, url string, f *os.File) (n int64, err error) {
+	err = retry(ctx, url, func() error {
+		if _, errF := f.Seek(0, io.SeekStart); errF != nil {
+			return errF
+		}
+		if errF := f.Truncate(0); errF != nil {
+			return errF
+		}
+		resp, errDo := do(ctx, url)
+		if errDo != nil {
+			return errDo
+		}
+		defer resp.Body.Close()
+		n, errDo = io.Copy(f, resp.Body)
+		return errDo
+	})
+	return
+}
+
+// do sends one GET request for `url`
+func do(ctx context.Context, url string) (resp *http.Response, err error) {
+	timeout := retryPolicy(ctx).Timeout
+	ctx, cancel := context.WithCancel(ctx)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		cancel()
+		return
+	}
+	req.Header.Set("User-Agent", userAgent)
+	h, _ := ctx.Value(hostLimiterKey).(*HostLimiter)
+	if h != nil {
+		if err = h.Wait(ctx, req.URL.Host); err != nil {
+			cancel()
+			return
+		}
+	}
+	log.Debugf("GET %s", url)
+	body := &timeoutBody{url: url, timeout: timeout, cancel: cancel}
+	if timeout > 0 {
+		body.timer = time.AfterFunc(timeout, body.expire)
+	}
+	resp, err = httpClient.Do(req)
+	if err != nil {
+		body.Close()
+		err = body.wrap(err)
+		return
+	}
+	body.ReadCloser = resp.Body
+	resp.Body = body
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		statusErr := &StatusError{
+			URL:        url,
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		}
+		resp.Body.Close()
+		// Everyone else slows down too, not only the request which was told to
+		if h != nil && statusErr.RetryAfter > 0 {
+			h.Delay(req.URL.Host, statusErr.RetryAfter)
+		}
+		return nil, statusErr
+	}
+	if r, ok := ctx.Value(rateLimiterKey).(*RateLimiter); ok {
+		resp.Body = limitedBody{Reader: r.Reader(ctx, resp.Body), Closer: resp.Body}
+	}
+	return
+}
+
+// timeoutBody is a response body which cancels its request if no data arrives for a while
+//
+// Its timer starts with the request, so it also covers waiting for the response
+type timeoutBody struct {
+	io.ReadCloser
+	url     string
+	timeout time.Duration
+	timer   *time.Timer
+	cancel  context.CancelFunc
+	expired int32
+}
+
+// expire cancels the request, it is called by the timer
+func (b *timeoutBody) expire() {
+	atomic.StoreInt32(&b.expired, 1)
+	b.cancel()
+}
+
+// wrap returns a TimeoutError instead of `err` if the request was cancelled by the timer
+func (b *timeoutBody) wrap(err error) error {
+	if err != nil && atomic.LoadInt32(&b.expired) == 1 {
+		return &TimeoutError{URL: b.url, Timeout: b.timeout}
+	}
+	return err
+}
+
+// Read reads from the body and restarts the timer
+func (b *timeoutBody) Read(p []byte) (n int, err error) {
+	n, err = b.ReadCloser.Read(p)
+	if b.timer != nil && b.timer.Stop() {
+		b.timer.Reset(b.timeout)
+	}
+	return n, b.wrap(err)
+}
+
+// Close closes the body and releases the request
+func (b *timeoutBody) Close() (err error) {
+	if b.timer != nil {
+		b.timer.Stop()
+	}
+	if b.ReadCloser != nil {
+		err = b.ReadCloser.Close()
+	}
+	b.cancel()
+	return
+}
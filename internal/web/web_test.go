@@ -0,0 +1,93 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testRetry retries quickly, so tests do not wait long
+var testRetry = RetryPolicy{
+	Attempts:      3,
+	MinDelay:      time.Millisecond,
+	MaxDelay:      5 * time.Millisecond,
+	MaxRetryAfter: 2 * time.Second,
+	Timeout:       100 * time.Millisecond,
+}
+
+func TestFetchRetry(t *testing.T) {
+	ctx := WithRetryPolicy(context.Background(), testRetry)
+	var tests = []struct {
+		name     string
+		statuses []int
+		hits     int32
+		ok       bool
+	}{
+		{"server errors", []int{503, 502, 200}, 3, true},
+		{"too many failures", []int{500, 500, 500, 200}, 3, false},
+		{"not found", []int{404, 200}, 1, false},
+		{"timeout", []int{0, 200}, 2, true},
+		{"rate limited", []int{429, 200}, 2, true},
+	}
+	for _, tt := range tests {
+		var hits int32
+		statuses := tt.statuses
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch status := statuses[atomic.AddInt32(&hits, 1)-1]; status {
+			case http.StatusOK:
+				w.Write([]byte("ok"))
+			case http.StatusTooManyRequests:
+				w.Header().Set("Retry-After", "1")
+				w.WriteHeader(status)
+			case 0:
+				// Stall longer than the timeout
+				time.Sleep(200 * time.Millisecond)
+			default:
+				w.WriteHeader(status)
+			}
+		}))
+		start := time.Now()
+		body, err := Fetch(ctx, srv.URL)
+		elapsed := time.Since(start)
+		srv.Close()
+		if tt.ok && (err != nil || string(body) != "ok") {
+			t.Errorf("%s: expected ok, got %q (%v)", tt.name, body, err)
+		} else if !tt.ok && err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+		if n := atomic.LoadInt32(&hits); n != tt.hits {
+			t.Errorf("%s: expected %d requests, got %d", tt.name, tt.hits, n)
+		}
+		if tt.statuses[0] == http.StatusTooManyRequests && elapsed < time.Second {
+			t.Errorf("%s: Retry-After was not honoured, took %s", tt.name, elapsed)
+		}
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	var tests = map[error]bool{
+		&StatusError{StatusCode: 404}: false,
+		&StatusError{StatusCode: 429}: true,
+		&StatusError{StatusCode: 503}: true,
+		&StatusError{StatusCode: 501}: false,
+		&TimeoutError{}:               true,
+		context.Canceled:              false,
+		context.DeadlineExceeded:      false,
+		&StatusError{StatusCode: 408}: true,
+	}
+	for err, expected := range tests {
+		if Retryable(err) != expected {
+			t.Errorf("%#v: expected retryable %v", err, expected)
+		}
+	}
+	if d := parseRetryAfter("120"); d != 2*time.Minute {
+		t.Errorf("Expected 2m, got %s", d)
+	}
+	date := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	if d := parseRetryAfter(date); d < 58*time.Second || d > time.Minute {
+		t.Errorf("Expected about 1m for %s, got %s", date, d)
+	}
+}
@@ -0,0 +1,242 @@
+package library
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf16"
+
+	"github.com/cosandr/go-beat-playlist/playlist"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// cacheName is the file name of our hash cache in the user cache dir
+	cacheName = "hashes.json"
+	// songCoreCache is the path of SongCore's hash cache relative to the game folder
+	songCoreCache = "UserData/SongCore/SongHashData.dat"
+	// cacheVersion is bumped whenever the way hashes are calculated or what is cached changes, older caches are ignored
+	cacheVersion = 4
+	// fileTimeOffset is the number of 100ns intervals between 1601-01-01 and the Unix epoch
+	fileTimeOffset = 116444736000000000
+)
+
+// DefaultCachePath returns the path of our hash cache in the user cache dir, empty if there is none
+func DefaultCachePath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, configDirName, cacheName)
+}
+
+// cacheFile is the structure of our cache file
+type cacheFile struct {
+	Version int                   `json:"version"`
+	Entries map[string]cacheEntry `json:"entries"`
+}
+
+// cacheEntry is a hashed song folder in our cache, valid as long as its size and modification time are unchanged
+//
+// Stats holds the analysis of each difficulty file, if it was done
+type cacheEntry struct {
+	Hash    string                       `json:"hash"`
+	ModTime int64                        `json:"modTime"`
+	Size    int64                        `json:"size"`
+	Stats   map[string]playlist.MapStats `json:"stats,omitempty"`
+	Audio   *playlist.AudioInfo          `json:"audio,omitempty"`
+}
+
+// songCoreEntry is a hashed song folder in SongCore's SongHashData.dat
+type songCoreEntry struct {
+	DirectoryHash int64  `json:"directoryHash"`
+	SongHash      string `json:"songHash"`
+}
+
+// folderStat holds what a song folder's cache entries are checked against
+type folderStat struct {
+	modTime int64
+	size    int64
+	// dirHash is SongCore's directory hash, only set if file creation times are available
+	dirHash    int64
+	hasDirHash bool
+}
+
+// hashCache looks up song hashes in our cache and SongCore's, it is safe for concurrent use
+type hashCache struct {
+	entries      map[string]cacheEntry
+	mu           sync.Mutex
+	path         string
+	seen         map[string]struct{}
+	songCore     map[string]songCoreEntry
+	songCoreTime int64
+}
+
+// loadHashCache reads our cache at `path` and SongCore's cache at `songCorePath`, missing files are ignored
+func loadHashCache(path string, songCorePath string) *hashCache {
+	c := &hashCache{
+		entries:  make(map[string]cacheEntry),
+		path:     path,
+		seen:     make(map[string]struct{}),
+		songCore: make(map[string]songCoreEntry),
+	}
+	if file, err := ioutil.ReadFile(path); err == nil {
+		var cf cacheFile
+		if err = json.Unmarshal(file, &cf); err != nil {
+			log.Warnf("Ignoring hash cache %s: %v", path, err)
+		} else if cf.Version != cacheVersion {
+			log.Infof("Hash cache %s is outdated, songs will be hashed again", path)
+		} else if cf.Entries != nil {
+			c.entries = cf.Entries
+		}
+	}
+	if songCorePath == "" {
+		return c
+	}
+	info, err := os.Stat(songCorePath)
+	if err != nil {
+		return c
+	}
+	file, err := ioutil.ReadFile(songCorePath)
+	if err != nil {
+		log.Warnf("Cannot read SongCore cache: %v", err)
+		return c
+	}
+	var raw map[string]songCoreEntry
+	if err = json.Unmarshal(file, &raw); err != nil {
+		log.Warnf("Ignoring SongCore cache %s: %v", songCorePath, err)
+		return c
+	}
+	for k, v := range raw {
+		c.songCore[songCoreKey(k)] = v
+	}
+	c.songCoreTime = info.ModTime().UnixNano()
+	log.Debugf("Read %d SongCore hashes from %s", len(raw), songCorePath)
+	return c
+}
+
+// lookup returns the cache entry of the song in `folder`, if one matches `st`
+//
+// Our own cache is checked first, then SongCore's, which only has hashes
+func (c *hashCache) lookup(folder string, st folderStat) (cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seen[folder] = struct{}{}
+	if e, ok := c.entries[folder]; ok && e.Size == st.size && e.ModTime == st.modTime {
+		return e, true
+	}
+	e, ok := c.songCore[songCoreKey(folder)]
+	if !ok || e.SongHash == "" {
+		return cacheEntry{}, false
+	}
+	// Without creation times SongCore's entry is trusted if it was written after the folder last changed
+	if st.hasDirHash && e.DirectoryHash != st.dirHash {
+		return cacheEntry{}, false
+	} else if !st.hasDirHash && st.modTime > c.songCoreTime {
+		return cacheEntry{}, false
+	}
+	entry := cacheEntry{Hash: strings.ToLower(e.SongHash), ModTime: st.modTime, Size: st.size}
+	c.entries[folder] = entry
+	return entry, true
+}
+
+// store saves the hash and difficulty stats of the song in `folder`
+func (c *hashCache) store(folder string, st folderStat, hash string, stats map[string]playlist.MapStats,
+	audio *playlist.AudioInfo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seen[folder] = struct{}{}
+	c.entries[folder] = cacheEntry{Hash: hash, ModTime: st.modTime, Size: st.size, Stats: stats, Audio: audio}
+}
+
+// save writes our cache, dropping entries in `roots` which were not looked up since it was loaded
+//
+// Entries outside `roots` belong to other profiles and are kept
+func (c *hashCache) save(roots []string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, root := range roots {
+		prefix := filepath.Clean(root) + string(filepath.Separator)
+		for k := range c.entries {
+			if _, ok := c.seen[k]; !ok && strings.HasPrefix(k, prefix) {
+				delete(c.entries, k)
+			}
+		}
+	}
+	file, err := json.Marshal(cacheFile{Version: cacheVersion, Entries: c.entries})
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+	// Write to a temporary file first, an interrupted write must not corrupt the cache
+	tmp := c.path + ".tmp"
+	if err = ioutil.WriteFile(tmp, file, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.path)
+}
+
+// songCoreKey returns the key SongCore cache entries are matched by, the song folder and its parent
+//
+// SongCore keys are Windows paths, which do not match ours on Linux or under Proton
+func songCoreKey(path string) string {
+	parts := strings.Split(strings.ReplaceAll(path, "\\", "/"), "/")
+	if len(parts) > 2 {
+		parts = parts[len(parts)-2:]
+	}
+	return strings.ToLower(strings.Join(parts, "/"))
+}
+
+// statFolder returns the total size and latest modification time of the files in `folder`
+//
+// Also calculates SongCore's directory hash if the platform has file creation times
+func statFolder(folder string) (st folderStat, err error) {
+	dir, err := os.Stat(folder)
+	if err != nil {
+		return
+	}
+	files, err := ioutil.ReadDir(folder)
+	if err != nil {
+		return
+	}
+	st.modTime = dir.ModTime().UnixNano()
+	st.hasDirHash = true
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		st.size += f.Size()
+		if t := f.ModTime().UnixNano(); t > st.modTime {
+			st.modTime = t
+		}
+		created, ok := creationTime(f)
+		if !ok {
+			st.hasDirHash = false
+			continue
+		}
+		st.dirHash ^= fileTime(created)
+		st.dirHash ^= fileTime(f.ModTime())
+		st.dirHash ^= int64(sumCharacters(f.Name()))
+		st.dirHash ^= f.Size()
+	}
+	return
+}
+
+// fileTime returns `t` as a Windows FILETIME
+func fileTime(t time.Time) int64 {
+	return t.UnixNano()/100 + fileTimeOffset
+}
+
+// sumCharacters returns the sum of the UTF-16 code units of `s`, overflowing like a C# int
+func sumCharacters(s string) (sum int32) {
+	for _, c := range utf16.Encode([]rune(s)) {
+		sum += int32(c)
+	}
+	return
+}
@@ -0,0 +1,118 @@
+package library
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const sampleHash = "9bf202f68c333421c69ca6aa15c648d65d4a1e0f"
+
+func TestHashCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "library")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	songs := filepath.Join(dir, "CustomLevels")
+	folder := filepath.Join(songs, "song")
+	copySample(t, folder)
+	cachePath := filepath.Join(dir, "cache", cacheName)
+
+	readHash := func(songCorePath string) string {
+		cache := loadHashCache(cachePath, songCorePath)
+		p, err := readInstalledSongs(songs, 1, nil, cache)
+		if err != nil {
+			t.Fatalf("Reading songs failed: %v", err)
+		}
+		if err = cache.save([]string{songs}); err != nil {
+			t.Fatalf("Saving cache failed: %v", err)
+		}
+		if len(p.Songs) != 1 {
+			t.Fatalf("Expected 1 song, got %d", len(p.Songs))
+		}
+		return p.Songs[0].Hash
+	}
+	if h := readHash(""); h != sampleHash {
+		t.Fatalf("Expected hash %s, got %s", sampleHash, h)
+	}
+	// An up to date entry is used as is
+	st, err := statFolder(folder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache := loadHashCache(cachePath, "")
+	e, ok := cache.lookup(folder, st)
+	if !ok || e.Hash != sampleHash {
+		t.Fatalf("Expected cached hash %s, got %s", sampleHash, e.Hash)
+	}
+	if len(e.Stats) != 4 {
+		t.Errorf("Expected stats of 4 difficulties, got %d", len(e.Stats))
+	}
+	cache.store(folder, st, "cached", e.Stats, e.Audio)
+	if err = cache.save([]string{songs}); err != nil {
+		t.Fatal(err)
+	}
+	if h := readHash(""); h != "cached" {
+		t.Errorf("Expected cached hash, got %s", h)
+	}
+	// A changed folder is hashed again
+	later := time.Now().Add(time.Minute)
+	if err = os.Chtimes(filepath.Join(folder, "Hard.dat"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	if h := readHash(""); h != sampleHash {
+		t.Errorf("Expected hash %s after change, got %s", sampleHash, h)
+	}
+}
+
+func TestSongCoreCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "library")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	folder := filepath.Join(dir, "CustomLevels", "song")
+	copySample(t, folder)
+	songCorePath := filepath.Join(dir, "SongHashData.dat")
+	data := `{"C:\\Beat Saber\\Beat Saber_Data\\CustomLevels\\song": {"directoryHash": 0, "songHash": "ABCDEF"}}`
+	if err = ioutil.WriteFile(songCorePath, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	st, err := statFolder(folder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.hasDirHash {
+		t.Skip("SongCore directory hash not faked on platforms with creation times")
+	}
+	// Written after the folder changed
+	later := time.Now().Add(time.Minute)
+	if err = os.Chtimes(songCorePath, later, later); err != nil {
+		t.Fatal(err)
+	}
+	cache := loadHashCache(filepath.Join(dir, cacheName), songCorePath)
+	if e, ok := cache.lookup(folder, st); !ok || e.Hash != "abcdef" {
+		t.Errorf("Expected SongCore hash abcdef, got %q", e.Hash)
+	}
+	// Written before the folder changed
+	earlier := time.Now().Add(-time.Hour)
+	if err = os.Chtimes(songCorePath, earlier, earlier); err != nil {
+		t.Fatal(err)
+	}
+	cache = loadHashCache(filepath.Join(dir, cacheName), songCorePath)
+	if e, ok := cache.lookup(folder, st); ok {
+		t.Errorf("Expected outdated SongCore entry to be ignored, got %q", e.Hash)
+	}
+}
+
+func TestSumCharacters(t *testing.T) {
+	// Summed as UTF-16 code units, characters outside the BMP count as two
+	for s, want := range map[string]int32{"Info.dat": 755, "\U0001F3B5": 0xD83C + 0xDFB5} {
+		if sum := sumCharacters(s); sum != want {
+			t.Errorf("Expected %d for %q, got %d", want, s, sum)
+		}
+	}
+}
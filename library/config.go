@@ -0,0 +1,290 @@
+package library
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/cosandr/go-beat-playlist/internal/fsutil"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// configName is the config file name looked for in each search directory
+	configName = "config.json"
+	// configDirName is the directory in the user config dir holding our config
+	configDirName = "go-beat-playlist"
+	// EnvPrefix is the prefix of all environment variables we read
+	EnvPrefix = "BEAT_PLAYLIST_"
+	// defaultGame is the game folder of a default Steam install on Windows
+	defaultGame = "C:/Program Files (x86)/Steam/steamapps/common/Beat Saber"
+)
+
+// Config is the internal config, storing various game paths of the active profile
+type Config struct {
+	Base         string
+	DeletedSongs string
+	Playlists    string
+	Profile      string
+	Songs        string
+}
+
+// ConfigSearchPath returns the config file candidates in order of priority
+//
+// `flagPath` comes first if set, then $BEAT_PLAYLIST_CONFIG, the user config dir and lastly the working directory
+func ConfigSearchPath(flagPath string) []string {
+	var paths []string
+	if flagPath != "" {
+		paths = append(paths, flagPath)
+	}
+	if env := os.Getenv(EnvPrefix + "CONFIG"); env != "" {
+		paths = append(paths, env)
+	}
+	if dir, err := os.UserConfigDir(); err == nil {
+		paths = append(paths, filepath.Join(dir, configDirName, configName))
+	}
+	return append(paths, "./"+configName)
+}
+
+// FindConfig returns the first existing config file in the search path
+//
+// An explicitly given path is returned even if it does not exist yet, otherwise defaults to the working directory
+func FindConfig(flagPath string) string {
+	paths := ConfigSearchPath(flagPath)
+	if flagPath != "" || os.Getenv(EnvPrefix+"CONFIG") != "" {
+		return paths[0]
+	}
+	for _, p := range paths {
+		if fsutil.FileExists(p) {
+			return p
+		}
+	}
+	return paths[len(paths)-1]
+}
+
+// ConfigFromEnv returns config values set in BEAT_PLAYLIST_* environment variables
+func ConfigFromEnv() ProfileJSON {
+	return ProfileJSON{
+		Game:         os.Getenv(EnvPrefix + "GAME"),
+		Songs:        os.Getenv(EnvPrefix + "SONGS"),
+		Playlists:    os.Getenv(EnvPrefix + "PLAYLISTS"),
+		DeletedSongs: os.Getenv(EnvPrefix + "DELETED"),
+	}
+}
+
+// Override returns a copy of jc with all non-empty fields of `o` replacing its own
+func (jc ProfileJSON) Override(o ProfileJSON) ProfileJSON {
+	if o.Game != "" {
+		jc.Game = o.Game
+	}
+	if o.Songs != "" {
+		jc.Songs = o.Songs
+	}
+	if o.Playlists != "" {
+		jc.Playlists = o.Playlists
+	}
+	if o.DeletedSongs != "" {
+		jc.DeletedSongs = o.DeletedSongs
+	}
+	return jc
+}
+
+// AskGameFunc is called by NewConfig when it needs the game folder but cannot find it on its own
+//
+// `candidates` holds the installs found by DiscoverGame if there are several, otherwise `notFound` is the last
+// path tried. Returns the game folder to try next.
+type AskGameFunc func(notFound string, candidates []string) (string, error)
+
+// NewConfig reads the config at `path` and returns a `Config` object for `profile`
+//
+// An empty `profile` selects the config's default profile, if any. Values in `override` take precedence over
+// the file. Will check for valid game path, creating missing directories (Playlists/CustomLevels). If the game
+// is not configured it is looked for in Steam libraries. `ask` is used when that fails, the chosen path is
+// saved to the config file. A nil `ask` returns an error instead.
+func NewConfig(path string, profile string, override ProfileJSON, ask AskGameFunc) (c Config, err error) {
+	var cj ConfigJSON
+	file, err := ioutil.ReadFile(path)
+	if err == nil {
+		errJ := json.Unmarshal(file, &cj)
+		if errJ != nil {
+			err = fmt.Errorf("Cannot parse %s: %v", path, errJ)
+			return
+		}
+	} else {
+		// Try to run without config file
+		err = nil
+	}
+	c.Profile, err = cj.selectProfile(profile)
+	if err != nil {
+		err = fmt.Errorf("%v in %s", err, path)
+		return
+	}
+	jc := cj.ProfileJSON.Override(cj.Profiles[c.Profile]).Override(override)
+	// The game folder is only needed if a path must be derived from it
+	needGame := jc.Songs == "" || jc.Playlists == "" || jc.DeletedSongs == ""
+	// Check for valid game path
+	var asked bool
+	if len(jc.Game) > 0 {
+		c.Base = NewPath(jc.Game)
+	} else if needGame {
+		games := DiscoverGame()
+		switch len(games) {
+		case 0:
+			// Default to C Steam folder
+			c.Base = NewPath(defaultGame)
+		case 1:
+			c.Base = games[0]
+			log.Infof("found game at %s", c.Base)
+		default:
+			if ask == nil {
+				err = fmt.Errorf("found several games (%s), set one with %sGAME or in %s",
+					strings.Join(games, ", "), EnvPrefix, path)
+				return
+			}
+			if c.Base, err = ask("", games); err != nil {
+				return
+			}
+			c.Base = NewPath(c.Base)
+			asked = true
+		}
+	}
+	for needGame && !fsutil.FileExists(c.Base+"/Beat Saber.exe") {
+		if ask == nil {
+			err = fmt.Errorf("game not found at %s, set it with %sGAME or in %s", c.Base, EnvPrefix, path)
+			return
+		}
+		if c.Base, err = ask(c.Base, nil); err != nil {
+			return
+		}
+		c.Base = NewPath(c.Base)
+		asked = true
+	}
+	// Write to config
+	if asked {
+		writeConfigGame(path, c.Profile, c.Base)
+	}
+	mkdirMap := map[string]string{
+		"Playlists":     c.Base + "/Playlists",
+		"Custom songs":  c.Base + "/Beat Saber_Data/CustomLevels",
+		"Deleted songs": c.Base + "/DeletedSongs",
+	}
+	explicit := map[string]string{
+		"Playlists":     jc.Playlists,
+		"Custom songs":  jc.Songs,
+		"Deleted songs": jc.DeletedSongs,
+	}
+	for k, v := range explicit {
+		if v != "" {
+			mkdirMap[k] = NewPath(v)
+		}
+	}
+	for k, v := range mkdirMap {
+		if !fsutil.DirExists(v) {
+			err = os.MkdirAll(v, 0755)
+			if err != nil {
+				return
+			}
+			log.Infof("%s folder %s created", k, v)
+		}
+	}
+	c.Playlists = mkdirMap["Playlists"]
+	c.Songs = mkdirMap["Custom songs"]
+	c.DeletedSongs = mkdirMap["Deleted songs"]
+	return
+}
+
+// selectProfile returns the name of the profile to use, `profile` if set or the default profile otherwise
+//
+// Returns an empty name if there are no profiles to choose from
+func (cj *ConfigJSON) selectProfile(profile string) (string, error) {
+	if profile == "" {
+		profile = cj.DefaultProfile
+	}
+	if profile == "" {
+		return "", nil
+	}
+	if _, ok := cj.Profiles[profile]; !ok {
+		var names []string
+		for name := range cj.Profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return "", fmt.Errorf("profile %q not found, have [%s]", profile, strings.Join(names, ", "))
+	}
+	return profile, nil
+}
+
+// writeConfigGame saves `game` for `profile` in the config file at `path`, keeping its other values
+//
+// An empty `profile` saves it at the top level
+func writeConfigGame(path string, profile string, game string) {
+	var cj ConfigJSON
+	if file, err := ioutil.ReadFile(path); err == nil {
+		if err = json.Unmarshal(file, &cj); err != nil {
+			log.Errorf("cannot update config file: %v", err)
+			return
+		}
+	}
+	if profile == "" {
+		cj.Game = game
+	} else {
+		p := cj.Profiles[profile]
+		p.Game = game
+		cj.Profiles[profile] = p
+	}
+	file, err := json.MarshalIndent(&cj, "", " ")
+	if err != nil {
+		log.Errorf("cannot marshal config file: %v", err)
+		return
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err == nil {
+		err = ioutil.WriteFile(path, file, 0644)
+	}
+	if err != nil {
+		log.Errorf("cannot write config file: %v", err)
+		return
+	}
+	log.Infof("updated config file %s", path)
+}
+
+// ConfigJSON is the structure of the config.json file
+//
+// Top level paths are used when no profile is active, and as defaults for paths a profile leaves empty
+type ConfigJSON struct {
+	ProfileJSON
+	DefaultProfile string                 `json:"defaultProfile,omitempty"`
+	Profiles       map[string]ProfileJSON `json:"profiles,omitempty"`
+}
+
+// ProfileJSON is the structure of an install profile in config.json
+type ProfileJSON struct {
+	Game         string `json:"game,omitempty"`
+	Songs        string `json:"songs,omitempty"`
+	Playlists    string `json:"playlists,omitempty"`
+	DeletedSongs string `json:"deletedSongs,omitempty"`
+}
+
+// NewPath does nothing on Windows, replaces C: with /mnt/c and all \ with / on Linux
+//
+// Drive letters are mapped to WSL mounts, for paths read from Windows Steam's library folders
+func NewPath(path string) string {
+	ret := path
+	if runtime.GOOS == "linux" {
+		re := regexp.MustCompile(`^(\w):[\\/]`)
+		m := re.FindStringSubmatchIndex(ret)
+		if len(m) == 4 {
+			// Find index of C:\ and replace it with /mnt/c/
+			// Works for drive other letters
+			ret = fmt.Sprintf("/mnt/%s/%s", strings.ToLower(ret[m[2]:m[3]]), ret[m[1]:])
+		}
+		// Strip all forward slashes
+		ret = strings.ReplaceAll(ret, "\\", "/")
+	}
+	return ret
+}
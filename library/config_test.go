@@ -0,0 +1,243 @@
+package library
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setEnv sets the environment variables in `env`, an empty value unsets one, and returns a function restoring them
+func setEnv(t *testing.T, env map[string]string) func() {
+	old := make(map[string]*string)
+	for k, v := range env {
+		if prev, ok := os.LookupEnv(k); ok {
+			old[k] = &prev
+		} else {
+			old[k] = nil
+		}
+		var err error
+		if v == "" {
+			err = os.Unsetenv(k)
+		} else {
+			err = os.Setenv(k, v)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return func() {
+		for k, v := range old {
+			if v == nil {
+				os.Unsetenv(k)
+			} else {
+				os.Setenv(k, *v)
+			}
+		}
+	}
+}
+
+// configEnv returns the environment for config tests in `dir`, no BEAT_PLAYLIST_* variables and no Steam installs
+func configEnv(dir string) map[string]string {
+	return map[string]string{
+		"HOME":                  dir,
+		"XDG_CONFIG_HOME":       filepath.Join(dir, "user"),
+		EnvPrefix + "CONFIG":    "",
+		EnvPrefix + "GAME":      "",
+		EnvPrefix + "SONGS":     "",
+		EnvPrefix + "PLAYLISTS": "",
+		EnvPrefix + "DELETED":   "",
+	}
+}
+
+func TestFindConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	if err = os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	userConfig := filepath.Join(dir, "user", configDirName, configName)
+	tests := []struct {
+		name     string
+		flagPath string
+		env      string
+		// files are created relative to the temp dir
+		files    []string
+		expected string
+	}{
+		{"nothing defaults to working dir", "", "", nil, "./" + configName},
+		{"working dir", "", "", []string{configName}, "./" + configName},
+		{"user dir before working dir", "", "", []string{configName, "user/" + configDirName + "/" + configName}, userConfig},
+		{"env before user dir", "", "env.json", []string{"user/" + configDirName + "/" + configName}, "env.json"},
+		{"env even if missing", "", "missing.json", nil, "missing.json"},
+		{"flag before env", "flag.json", "env.json", []string{"env.json"}, "flag.json"},
+	}
+	for _, tt := range tests {
+		env := configEnv(dir)
+		env[EnvPrefix+"CONFIG"] = tt.env
+		restore := setEnv(t, env)
+		for _, f := range tt.files {
+			if err = os.MkdirAll(filepath.Dir(f), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err = ioutil.WriteFile(f, []byte("{}"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if got := FindConfig(tt.flagPath); got != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.expected, got)
+		}
+		for _, f := range tt.files {
+			os.Remove(f)
+		}
+		restore()
+	}
+}
+
+func TestNewConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer setEnv(t, configEnv(dir))()
+	// Two games, only the main one is installed
+	game := filepath.ToSlash(filepath.Join(dir, "main"))
+	other := filepath.ToSlash(filepath.Join(dir, "other"))
+	if err = os.MkdirAll(game, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(game+"/Beat Saber.exe", nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	profiles := fmt.Sprintf(`{
+		"game": %q,
+		"playlists": %q,
+		"defaultProfile": "modded",
+		"profiles": {
+			"modded": {"game": %q},
+			"vanilla": {"game": %q, "songs": %q}
+		}
+	}`, game, dir+"/shared", game, other, dir+"/vanilla")
+	askMain := func(notFound string, candidates []string) (string, error) { return game, nil }
+
+	tests := []struct {
+		name    string
+		config  string
+		profile string
+		env     map[string]string
+		ask     AskGameFunc
+		// expected holds the wanted profile, then the songs and playlists folders
+		expected []string
+		err      string
+		// discovers is set if the result depends on no game being found in Steam libraries
+		discovers bool
+	}{
+		{
+			name:     "top level only",
+			config:   fmt.Sprintf(`{"game": %q}`, game),
+			expected: []string{"", game + "/Beat Saber_Data/CustomLevels", game + "/Playlists"},
+		},
+		{
+			name:     "default profile falls back to top level paths",
+			config:   profiles,
+			expected: []string{"modded", game + "/Beat Saber_Data/CustomLevels", dir + "/shared"},
+		},
+		{
+			name:     "env overrides profile",
+			config:   profiles,
+			env:      map[string]string{EnvPrefix + "SONGS": dir + "/env-songs"},
+			expected: []string{"modded", dir + "/env-songs", dir + "/shared"},
+		},
+		{
+			name:     "env game",
+			config:   `{}`,
+			env:      map[string]string{EnvPrefix + "GAME": game},
+			expected: []string{"", game + "/Beat Saber_Data/CustomLevels", game + "/Playlists"},
+		},
+		{
+			name:    "unknown profile",
+			config:  profiles,
+			profile: "missing",
+			err:     `profile "missing" not found, have [modded, vanilla]`,
+		},
+		{
+			name:    "missing game without TTY",
+			config:  profiles,
+			profile: "vanilla",
+			err:     "game not found at " + other,
+		},
+		{
+			name:     "missing game asked",
+			config:   profiles,
+			profile:  "vanilla",
+			ask:      askMain,
+			expected: []string{"vanilla", dir + "/vanilla", dir + "/shared"},
+		},
+		{
+			name:      "nothing configured without TTY",
+			config:    `{}`,
+			err:       "game not found at " + NewPath(defaultGame),
+			discovers: true,
+		},
+		{
+			name:   "invalid config",
+			config: `{"game": `,
+			err:    "Cannot parse",
+		},
+	}
+	// WSL mounts of Windows drives are searched regardless of HOME
+	discovered := len(DiscoverGame()) > 0
+	for i, tt := range tests {
+		if tt.discovers && discovered {
+			continue
+		}
+		path := filepath.Join(dir, fmt.Sprintf("config-%d.json", i))
+		if err = ioutil.WriteFile(path, []byte(tt.config), 0644); err != nil {
+			t.Fatal(err)
+		}
+		restore := setEnv(t, tt.env)
+		c, err := NewConfig(path, tt.profile, ConfigFromEnv(), tt.ask)
+		restore()
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: expected error %q, got %v", tt.name, tt.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		got := []string{c.Profile, c.Songs, c.Playlists}
+		for j := range got {
+			if got[j] != tt.expected[j] {
+				t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, got)
+				break
+			}
+		}
+		if tt.ask == nil {
+			continue
+		}
+		// The asked game is saved in the profile
+		var cj ConfigJSON
+		file, err := ioutil.ReadFile(path)
+		if err == nil {
+			err = json.Unmarshal(file, &cj)
+		}
+		if err != nil || cj.Profiles[tt.profile].Game != game {
+			t.Errorf("%s: expected game %s saved in %s, got %v", tt.name, game, tt.profile, cj.Profiles[tt.profile])
+		}
+	}
+}
@@ -0,0 +1,16 @@
+package library
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// creationTime returns the birth time of `fi`
+func creationTime(fi os.FileInfo) (time.Time, bool) {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(st.Birthtimespec.Unix()), true
+}
@@ -0,0 +1,14 @@
+//go:build !windows && !darwin
+// +build !windows,!darwin
+
+package library
+
+import (
+	"os"
+	"time"
+)
+
+// creationTime is not available on this platform, SongCore's directory hash cannot be calculated
+func creationTime(fi os.FileInfo) (time.Time, bool) {
+	return time.Time{}, false
+}
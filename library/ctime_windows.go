@@ -0,0 +1,16 @@
+package library
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// creationTime returns the creation time of `fi`
+func creationTime(fi os.FileInfo) (time.Time, bool) {
+	d, ok := fi.Sys().(*syscall.Win32FileAttributeData)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(0, d.CreationTime.Nanoseconds()), true
+}
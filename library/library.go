@@ -0,0 +1,276 @@
+// Package library reads the songs and playlists of a Beat Saber install
+//
+// It finds the game through the config file and Steam library folders, and scans installed songs and playlists
+package library
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+
+	"github.com/cosandr/go-beat-playlist/playlist"
+	log "github.com/sirupsen/logrus"
+)
+
+// Library holds the config, installed songs and playlists of a profile
+//
+// It is safe for concurrent use, all getters return copies so readers never see a partial reload
+type Library struct {
+	conf      Config
+	mu        sync.RWMutex
+	playlists map[string]playlist.Playlist
+	// read holds the playlists as read from their files, before installed songs were merged into them
+	read  map[string]playlist.Playlist
+	songs playlist.Playlist
+	// installed indexes songs, listed indexes the songs of all playlists
+	installed *playlist.Index
+	listed    *playlist.Index
+	// folders holds the songs of each song folder, stamps the state song folders and playlists were read in
+	folders   map[string][]playlist.Song
+	stamps    map[string]stamp
+	cache     *hashCache
+	cachePath string
+	progress  ProgressFunc
+	// refreshMu serializes Load and Refresh, which read the library state outside of mu
+	refreshMu sync.Mutex
+	workers   int
+}
+
+// NewLibrary returns an empty Library for `c`, call Load to read its songs and playlists
+func NewLibrary(c Config) *Library {
+	return &Library{
+		conf:      c,
+		playlists: make(map[string]playlist.Playlist),
+		read:      make(map[string]playlist.Playlist),
+		songs:     playlist.Playlist{Title: "Installed Songs"},
+		installed: playlist.NewIndex(nil),
+		listed:    playlist.NewIndex(nil),
+		folders:   make(map[string][]playlist.Song),
+		stamps:    make(map[string]stamp),
+		cachePath: DefaultCachePath(),
+		workers:   runtime.NumCPU(),
+	}
+}
+
+// SetCachePath sets the file song hashes are cached in between loads, empty disables caching
+//
+// Defaults to DefaultCachePath. SongCore's cache in the game folder is read as well when caching is enabled.
+func (l *Library) SetCachePath(path string) {
+	l.cachePath = path
+}
+
+// SetWorkers sets the number of songs scanned and hashed in parallel by Load, defaults to the number of CPUs
+func (l *Library) SetWorkers(n int) {
+	if n < 1 {
+		n = runtime.NumCPU()
+	}
+	l.workers = n
+}
+
+// SetProgress sets a function called as Load scans song folders, nil disables it
+func (l *Library) SetProgress(fn ProgressFunc) {
+	l.progress = fn
+}
+
+// Load reads all installed songs and playlists, loaded data is only replaced if both succeed
+func (l *Library) Load() error {
+	l.refreshMu.Lock()
+	defer l.refreshMu.Unlock()
+	var cache *hashCache
+	if l.cachePath != "" {
+		var songCorePath string
+		if l.conf.Base != "" {
+			songCorePath = filepath.Join(l.conf.Base, songCoreCache)
+		}
+		cache = loadHashCache(l.cachePath, songCorePath)
+	}
+	roots := l.conf.SongFolders()
+	folders, names, err := listAllSongFolders(roots)
+	if err != nil {
+		return fmt.Errorf("cannot read installed songs: %v", err)
+	}
+	files, err := listPlaylistFiles(l.conf.Playlists)
+	if err != nil {
+		return fmt.Errorf("cannot read playlists: %v", err)
+	}
+	// Stamp before reading, changes made while reading are picked up by the next Refresh
+	stamps := make(map[string]stamp, len(folders)+len(files))
+	for _, path := range folders {
+		stamps[path] = folderStamp(path)
+	}
+	for _, path := range files {
+		stamps[path] = fileStamp(path)
+	}
+	byFolder := make(map[string][]playlist.Song, len(folders))
+	for i, songs := range scanSongFolders(folders, names, l.workers, l.progress, cache) {
+		byFolder[folders[i]] = songs
+	}
+	if cache != nil {
+		rootPaths := make([]string, len(roots))
+		for i, r := range roots {
+			rootPaths[i] = r.Path
+		}
+		if err = cache.save(rootPaths); err != nil {
+			log.Warnf("Cannot save hash cache: %v", err)
+		}
+	}
+	// Key by path, titles are not unique
+	byPath := make(map[string]playlist.Playlist, len(files))
+	for _, path := range files {
+		if p, ok := readPlaylist(path); ok {
+			byPath[path] = p
+		}
+	}
+	l.update(byFolder, byPath, stamps)
+	l.cache = cache
+	return nil
+}
+
+// update replaces the loaded state, merging installed songs into copies of the playlists as `read`
+func (l *Library) update(folders map[string][]playlist.Song, read map[string]playlist.Playlist, stamps map[string]stamp) {
+	paths := make([]string, 0, len(folders))
+	for path := range folders {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	songs := playlist.Playlist{Title: "Installed Songs"}
+	for _, path := range paths {
+		songs.Songs = append(songs.Songs, folders[path]...)
+	}
+	installed := songs.Index()
+	listed := playlist.NewIndex(nil)
+	playlists := make(map[string]playlist.Playlist, len(read))
+	for path, p := range read {
+		p = copyPlaylist(p)
+		p.Installed(installed)
+		playlists[path] = p
+		for _, s := range p.Songs {
+			listed.Add(s)
+		}
+	}
+	l.mu.Lock()
+	l.folders = folders
+	l.songs = songs
+	l.playlists = playlists
+	l.read = read
+	l.installed = installed
+	l.listed = listed
+	l.stamps = stamps
+	l.mu.Unlock()
+}
+
+// Reload reads everything again, for use after songs or playlists were changed
+func (l *Library) Reload() error {
+	return l.Load()
+}
+
+// Config returns the config this library was created with
+func (l *Library) Config() Config {
+	return l.conf
+}
+
+// Songs returns all installed songs
+func (l *Library) Songs() playlist.Playlist {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return copyPlaylist(l.songs)
+}
+
+// Playlists returns all playlists, sorted by file path
+func (l *Library) Playlists() []playlist.Playlist {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	ret := make([]playlist.Playlist, 0, len(l.playlists))
+	for _, p := range l.playlists {
+		ret = append(ret, copyPlaylist(p))
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].File < ret[j].File
+	})
+	return ret
+}
+
+// Playlist returns the playlist read from `path`
+func (l *Library) Playlist(path string) (playlist.Playlist, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	p, ok := l.playlists[path]
+	return copyPlaylist(p), ok
+}
+
+// FindPlaylists returns all playlists whose title, path or file name is `name`
+func (l *Library) FindPlaylists(name string) []playlist.Playlist {
+	var ret []playlist.Playlist
+	for _, p := range l.Playlists() {
+		if p.Title == name || p.File == name || filepath.Base(p.File) == name {
+			ret = append(ret, p)
+		}
+	}
+	return ret
+}
+
+// Counts returns the number of installed songs and playlists
+func (l *Library) Counts() (songs int, playlists int) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return len(l.songs.Songs), len(l.playlists)
+}
+
+// Installed returns the installed song equal to `s`, matched by hash or key
+func (l *Library) Installed(s *playlist.Song) (playlist.Song, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.installed.Find(s)
+}
+
+// Listed returns true if a song equal to `s` is in any playlist
+func (l *Library) Listed(s *playlist.Song) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.listed.Contains(s)
+}
+
+// Orphans returns a Playlist of songs not already in any playlists
+func (l *Library) Orphans() playlist.Playlist {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	var orphans []playlist.Song
+	for _, s := range l.songs.Songs {
+		if !l.listed.Contains(&s) {
+			orphans = append(orphans, s)
+		}
+	}
+	return playlist.Playlist{Title: "Orphans", Songs: orphans}
+}
+
+// Missing returns the songs which are not installed of each playlist, keyed by playlist path
+//
+// Official levels are never missing, playlists without missing songs are left out
+func (l *Library) Missing() map[string]playlist.Playlist {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	var missing = make(map[string]playlist.Playlist)
+	for path, p := range l.playlists {
+		songs := []playlist.Song{}
+		for _, s := range p.Songs {
+			// Official levels are always installed
+			if len(s.Path) == 0 && !s.IsBuiltIn() {
+				songs = append(songs, s)
+			}
+		}
+		if len(songs) > 0 {
+			m := p
+			m.Songs = songs
+			missing[path] = m
+		}
+	}
+	return missing
+}
+
+// copyPlaylist returns a copy of `p` with its own Songs slice
+func copyPlaylist(p playlist.Playlist) playlist.Playlist {
+	p.Songs = append([]playlist.Song(nil), p.Songs...)
+	return p
+}
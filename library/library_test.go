@@ -0,0 +1,117 @@
+package library
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+func TestLibraryLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "library")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	c := Config{Songs: filepath.Join(dir, "CustomLevels"), Playlists: filepath.Join(dir, "Playlists")}
+	if err = os.MkdirAll(c.Playlists, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.MkdirAll(c.Songs, 0755); err != nil {
+		t.Fatal(err)
+	}
+	// Two files with the same title must not overwrite each other
+	sample, err := ioutil.ReadFile("../samples/json/playlist.bplist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.bplist", "b.json"} {
+		if err = ioutil.WriteFile(filepath.Join(c.Playlists, name), sample, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Official levels are never missing
+	levels, err := ioutil.ReadFile("../samples/json/playlist-levels.bplist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(filepath.Join(c.Playlists, "c.bplist"), levels, 0644); err != nil {
+		t.Fatal(err)
+	}
+	lib := NewLibrary(c)
+	lib.SetCachePath("")
+	if err = lib.Load(); err != nil {
+		t.Fatalf("Library load failed: %v", err)
+	}
+	if _, numPlaylists := lib.Counts(); numPlaylists != 3 {
+		t.Errorf("Expected 3 playlists, got %d", numPlaylists)
+	}
+	if found := lib.FindPlaylists("Anniversary Song Pack"); len(found) != 2 {
+		t.Errorf("Expected 2 playlists titled Anniversary Song Pack, got %d", len(found))
+	}
+	var numMissing int
+	for _, p := range lib.Missing() {
+		numMissing += len(p.Songs)
+	}
+	if numMissing != 89 {
+		t.Errorf("Expected 89 missing songs, got %d", numMissing)
+	}
+}
+
+func TestReadInstalledSongs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "library")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// Copy the sample song into several folders, in reverse order of their names
+	names := []string{"e", "d", "c", "b", "a"}
+	for _, name := range names {
+		copySample(t, filepath.Join(dir, name))
+	}
+	var calls int32
+	p, err := readInstalledSongs(dir, 3, func(done int, total int, path string) {
+		atomic.AddInt32(&calls, 1)
+		if total != len(names) {
+			t.Errorf("Expected total %d, got %d", len(names), total)
+		}
+	}, nil)
+	if err != nil {
+		t.Fatalf("Reading songs failed: %v", err)
+	}
+	if int(calls) != len(names) {
+		t.Errorf("Expected %d progress calls, got %d", len(names), calls)
+	}
+	if len(p.Songs) != len(names) {
+		t.Fatalf("Expected %d songs, got %d", len(names), len(p.Songs))
+	}
+	for i, s := range p.Songs {
+		if filepath.Base(s.Path) != names[len(names)-1-i] {
+			t.Errorf("Expected song %d in %s, got %s", i, names[len(names)-1-i], s.Path)
+		}
+		if s.Hash != "9bf202f68c333421c69ca6aa15c648d65d4a1e0f" {
+			t.Errorf("Wrong hash %s for %s", s.Hash, s.Path)
+		}
+	}
+}
+
+// copySample copies the sample song into `dir`
+func copySample(t *testing.T, dir string) {
+	files, err := ioutil.ReadDir("../samples/song-nightraid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		data, err := ioutil.ReadFile(filepath.Join("../samples/song-nightraid", f.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = ioutil.WriteFile(filepath.Join(dir, f.Name()), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package library
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/cosandr/go-beat-playlist/playlist"
+	log "github.com/sirupsen/logrus"
+)
+
+// listPlaylistFiles returns the paths of all playlist files in `path`, warning about other files
+func listPlaylistFiles(path string) (files []string, err error) {
+	entries, err := ioutil.ReadDir(path)
+	if err != nil {
+		return
+	}
+	for _, file := range entries {
+		if !playlist.IsPlaylistFile(file.Name()) {
+			if !strings.HasSuffix(file.Name(), ".bak") {
+				log.Warnf("%s is not a valid playlist, skipping.", file.Name())
+			}
+			continue
+		}
+		files = append(files, path+"/"+file.Name())
+	}
+	return
+}
+
+// readPlaylist reads the playlist at `path`, warning if it cannot be read
+func readPlaylist(path string) (p playlist.Playlist, ok bool) {
+	p, err := playlist.MakePlaylist(path)
+	if err != nil {
+		log.Warnf("Cannot read playlist: %v", err)
+		return
+	}
+	return p, true
+}
+
+// ProgressFunc is called after each song folder is scanned, with the number of folders done out of `total`
+//
+// It may be called from several goroutines at once
+type ProgressFunc func(done int, total int, path string)
+
+// readInstalledSongs reads all songs in the `path` folder using `workers` goroutines
+//
+// Each top level folder is scanned and hashed by one worker, songs are returned in folder name order. Hashes are
+// looked up in `cache` first if it is not nil.
+func readInstalledSongs(path string, workers int, progress ProgressFunc, cache *hashCache) (p playlist.Playlist, err error) {
+	folders, err := listSongFolders(path)
+	if err != nil {
+		return
+	}
+	var songs []playlist.Song
+	for _, r := range scanSongFolders(folders, nil, workers, progress, cache) {
+		songs = append(songs, r...)
+	}
+	p = playlist.Playlist{Title: "Installed Songs", Songs: songs}
+	return
+}
+
+// listSongFolders returns the paths of all folders in `path`, sorted by name
+//
+// Hidden folders are skipped, they hold downloads which are not installed yet
+func listSongFolders(path string) (folders []string, err error) {
+	entries, err := ioutil.ReadDir(path)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		if e.IsDir() && !strings.HasPrefix(e.Name(), ".") {
+			folders = append(folders, filepath.Join(path, e.Name()))
+		}
+	}
+	return
+}
+
+// listAllSongFolders returns the song folders in each of `roots`, along with the name of the root each one is in
+//
+// Only the first root must be readable, the others are skipped with a warning
+func listAllSongFolders(roots []SongFolder) (folders []string, names map[string]string, err error) {
+	names = make(map[string]string)
+	for i, root := range roots {
+		found, listErr := listSongFolders(root.Path)
+		if listErr != nil {
+			if i == 0 {
+				return nil, nil, listErr
+			}
+			log.Warnf("Cannot read song folder %s: %v", root.Name, listErr)
+			continue
+		}
+		for _, f := range found {
+			names[f] = root.Name
+		}
+		folders = append(folders, found...)
+	}
+	return
+}
+
+// scanSongFolders reads the songs of each folder in `folders` using `workers` goroutines
+//
+// Songs are tagged with the folder name in `names`, if any. Returns the songs of each folder at the same index.
+func scanSongFolders(folders []string, names map[string]string, workers int, progress ProgressFunc, cache *hashCache) [][]playlist.Song {
+	if workers < 1 {
+		workers = 1
+	}
+	// Each worker writes only to its job's slot, keeping the order independent of scheduling
+	results := make([][]playlist.Song, len(folders))
+	jobs := make(chan int)
+	var done int32
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = readSongFolder(folders[i], cache)
+				for j := range results[i] {
+					results[i][j].Folder = names[folders[i]]
+				}
+				if progress != nil {
+					progress(int(atomic.AddInt32(&done, 1)), len(folders), folders[i])
+				}
+			}
+		}()
+	}
+	for i := range folders {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+// readSongFolder returns all songs found in `path`, a file is only read if it is an info.dat itself
+func readSongFolder(path string, cache *hashCache) (songs []playlist.Song) {
+	err := filepath.Walk(path, func(subpath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.ToLower(info.Name()) == "info.dat" {
+			s, makeErr := readSong(subpath, cache)
+			if makeErr != nil {
+				log.Warnf("Cannot create song: %v", makeErr)
+				return nil
+			}
+			songs = append(songs, s)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Warnf("Cannot read %s: %v", path, err)
+	}
+	return
+}
+
+// readSong returns the song of the info.dat at `infoPath` with its difficulties analysed
+//
+// The hash and analysis are taken from `cache` if it is up to date
+func readSong(infoPath string, cache *hashCache) (s playlist.Song, err error) {
+	if cache == nil {
+		if s, err = playlist.MakeSong(infoPath); err != nil {
+			return
+		}
+		analyze(&s)
+		return
+	}
+	s, err = playlist.ReadSong(infoPath)
+	if err != nil {
+		return
+	}
+	folder := filepath.Dir(infoPath)
+	st, err := statFolder(folder)
+	if err != nil {
+		return
+	}
+	entry, ok := cache.lookup(folder, st)
+	if ok {
+		s.Hash = entry.Hash
+		s.Audio = entry.Audio
+		if s.SetStats(entry.Stats) {
+			return
+		}
+	} else if err = s.CalcHashFrom(infoPath); err != nil {
+		return
+	}
+	analyze(&s)
+	cache.store(folder, st, s.Hash, s.StatsByFile(), s.Audio)
+	return
+}
+
+// analyze analyses the difficulties of `s`, a song with broken difficulty files is still usable
+func analyze(s *playlist.Song) {
+	if err := s.Analyze(); err != nil {
+		log.Warnf("Cannot analyse song: %v", err)
+	}
+}
@@ -0,0 +1,105 @@
+package library
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/cosandr/go-beat-playlist/internal/fsutil"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// songCoreFolders is the path of SongCore's extra song folders list relative to the game folder
+	songCoreFolders = "UserData/SongCore/folders.xml"
+	// wipFolder is the path of the work in progress songs folder relative to the game folder
+	wipFolder = "Beat Saber_Data/CustomWIPLevels"
+	// CustomLevels is the name of the main song folder
+	CustomLevels = "Custom Levels"
+	// CustomWIPLevels is the name of the work in progress songs folder
+	CustomWIPLevels = "Custom WIP Levels"
+)
+
+// SongFolder is a folder the game loads songs from
+type SongFolder struct {
+	Name string
+	Path string
+	WIP  bool
+}
+
+// foldersXML is the structure of SongCore's folders.xml
+type foldersXML struct {
+	Folders []struct {
+		Name string `xml:"Name"`
+		Path string `xml:"Path"`
+		Pack int    `xml:"Pack"`
+		WIP  string `xml:"WIP"`
+	} `xml:"folder"`
+}
+
+// ReadSongCoreFolders returns the extra song folders listed in SongCore's folders.xml at `path`
+//
+// Entries without a path are skipped, folders without a name are named after their path
+func ReadSongCoreFolders(path string) (folders []SongFolder, err error) {
+	file, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+	var fx foldersXML
+	if err = xml.Unmarshal(file, &fx); err != nil {
+		return
+	}
+	for _, f := range fx.Folders {
+		if strings.TrimSpace(f.Path) == "" {
+			continue
+		}
+		sf := SongFolder{
+			Name: strings.TrimSpace(f.Name),
+			Path: NewPath(strings.TrimSpace(f.Path)),
+			// Pack 1 adds the songs to the WIP pack
+			WIP: strings.EqualFold(strings.TrimSpace(f.WIP), "true") || f.Pack == 1,
+		}
+		if sf.Name == "" {
+			sf.Name = filepath.Base(sf.Path)
+		}
+		folders = append(folders, sf)
+	}
+	return
+}
+
+// SongFolders returns all folders the game loads songs from, the songs folder comes first
+//
+// CustomWIPLevels and the folders in SongCore's folders.xml are included if they exist. Folders listed more than
+// once are only returned the first time.
+func (c Config) SongFolders() []SongFolder {
+	folders := []SongFolder{{Name: CustomLevels, Path: c.Songs}}
+	if c.Base == "" {
+		return folders
+	}
+	folders = append(folders, SongFolder{Name: CustomWIPLevels, Path: filepath.Join(c.Base, wipFolder), WIP: true})
+	xmlPath := filepath.Join(c.Base, songCoreFolders)
+	if fsutil.FileExists(xmlPath) {
+		extra, err := ReadSongCoreFolders(xmlPath)
+		if err != nil {
+			log.Warnf("Cannot read SongCore folders: %v", err)
+		}
+		folders = append(folders, extra...)
+	}
+	var ret []SongFolder
+	seen := make(map[string]struct{})
+	for i, f := range folders {
+		key := filepath.Clean(f.Path)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		// The songs folder always exists, it is created by NewConfig
+		if i > 0 && !fsutil.DirExists(f.Path) {
+			log.Debugf("Song folder %s not found at %s", f.Name, f.Path)
+			continue
+		}
+		ret = append(ret, f)
+	}
+	return ret
+}
@@ -0,0 +1,83 @@
+package library
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestReadSongCoreFolders(t *testing.T) {
+	folders, err := ReadSongCoreFolders("../samples/songcore/folders.xml")
+	if err != nil {
+		t.Fatalf("Reading folders.xml failed: %v", err)
+	}
+	practice := `D:\BeatSaber\Practice`
+	if runtime.GOOS == "linux" {
+		practice = "/mnt/d/BeatSaber/Practice"
+	}
+	expected := []SongFolder{
+		{Name: "Practice", Path: practice},
+		{Name: "Mapping", Path: NewPath(`D:\BeatSaber\Mapping`), WIP: true},
+		{Name: "Songs", Path: "/home/user/Songs"},
+	}
+	if len(folders) != len(expected) {
+		t.Fatalf("Expected %d folders, got %v", len(expected), folders)
+	}
+	for i := range expected {
+		if folders[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected[i], folders[i])
+		}
+	}
+}
+
+func TestLibrarySongFolders(t *testing.T) {
+	dir, err := ioutil.TempDir("", "library")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	extra := filepath.Join(dir, "Extra")
+	c := Config{
+		Base:      dir,
+		Songs:     filepath.Join(dir, "Beat Saber_Data", "CustomLevels"),
+		Playlists: filepath.Join(dir, "Playlists"),
+	}
+	copySample(t, filepath.Join(c.Songs, "a"))
+	copySample(t, filepath.Join(dir, wipFolder, "b"))
+	copySample(t, filepath.Join(extra, "c"))
+	if err = os.MkdirAll(c.Playlists, 0755); err != nil {
+		t.Fatal(err)
+	}
+	xmlPath := filepath.Join(dir, songCoreFolders)
+	if err = os.MkdirAll(filepath.Dir(xmlPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	data := fmt.Sprintf("<folders><folder><Name>Extra</Name><Path>%s</Path></folder></folders>", extra)
+	if err = ioutil.WriteFile(xmlPath, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	lib := NewLibrary(c)
+	lib.SetCachePath("")
+	if err = lib.Load(); err != nil {
+		t.Fatalf("Library load failed: %v", err)
+	}
+	folders := make(map[string]string)
+	for _, s := range lib.Songs().Songs {
+		folders[filepath.Base(s.Path)] = s.Folder
+	}
+	expected := map[string]string{"a": CustomLevels, "b": CustomWIPLevels, "c": "Extra"}
+	if len(folders) != len(expected) {
+		t.Fatalf("Expected songs in %v, got %v", expected, folders)
+	}
+	for k, v := range expected {
+		if folders[k] != v {
+			t.Errorf("Expected song %s in %s, got %q", k, v, folders[k])
+		}
+	}
+	if n := len(lib.Orphans().Songs); n != 3 {
+		t.Errorf("Expected 3 orphans, got %d", n)
+	}
+}
@@ -0,0 +1,274 @@
+package library
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/cosandr/go-beat-playlist/internal/fsutil"
+	"github.com/cosandr/go-beat-playlist/playlist"
+	log "github.com/sirupsen/logrus"
+)
+
+// beatSaberAppID is Beat Saber's Steam app ID
+const beatSaberAppID = "620980"
+
+// VDF is a parsed Valve KeyValues (.vdf/.acf) object, keys are lowercase
+//
+// Values are either a string or a nested VDF
+type VDF map[string]interface{}
+
+// String returns the string value of `key`, empty if it is missing or not a string
+func (v VDF) String(key string) string {
+	s, _ := v[strings.ToLower(key)].(string)
+	return s
+}
+
+// Map returns the nested object at `key`, nil if it is missing or not an object
+func (v VDF) Map(key string) VDF {
+	m, _ := v[strings.ToLower(key)].(VDF)
+	return m
+}
+
+// ParseVDF parses Valve's text KeyValues format used by libraryfolders.vdf and appmanifest files
+func ParseVDF(r io.Reader) (VDF, error) {
+	p := vdfParser{r: bufio.NewReader(r)}
+	return p.object(true)
+}
+
+type vdfParser struct {
+	r *bufio.Reader
+}
+
+// token returns the next string, `{` or `}`, quoted is true for strings
+func (p *vdfParser) token() (tok string, quoted bool, err error) {
+	for {
+		c, _, errR := p.r.ReadRune()
+		if errR != nil {
+			return "", false, errR
+		}
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			continue
+		case c == '/':
+			// Comments start with //, skip to end of line
+			if _, errR = p.r.ReadString('\n'); errR != nil && errR != io.EOF {
+				return "", false, errR
+			}
+			continue
+		case c == '{' || c == '}':
+			return string(c), false, nil
+		case c == '"':
+			return p.quoted()
+		default:
+			// Unquoted tokens end at whitespace
+			var b strings.Builder
+			b.WriteRune(c)
+			for {
+				c, _, errR = p.r.ReadRune()
+				if errR == io.EOF || c == ' ' || c == '\t' || c == '\r' || c == '\n' {
+					return b.String(), true, nil
+				} else if errR != nil {
+					return "", false, errR
+				}
+				b.WriteRune(c)
+			}
+		}
+	}
+}
+
+// quoted reads a string up to the closing quote, handling escapes
+func (p *vdfParser) quoted() (string, bool, error) {
+	var b strings.Builder
+	for {
+		c, _, err := p.r.ReadRune()
+		if err != nil {
+			return "", false, fmt.Errorf("unterminated string: %v", err)
+		}
+		switch c {
+		case '"':
+			return b.String(), true, nil
+		case '\\':
+			n, _, err := p.r.ReadRune()
+			if err != nil {
+				return "", false, fmt.Errorf("unterminated string: %v", err)
+			}
+			switch n {
+			case 'n':
+				b.WriteRune('\n')
+			case 't':
+				b.WriteRune('\t')
+			default:
+				b.WriteRune(n)
+			}
+		default:
+			b.WriteRune(c)
+		}
+	}
+}
+
+// object reads key/value pairs until `}`, or EOF if `root` is true
+func (p *vdfParser) object(root bool) (VDF, error) {
+	obj := make(VDF)
+	for {
+		key, quoted, err := p.token()
+		if err == io.EOF && root {
+			return obj, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if !quoted {
+			if key == "}" && !root {
+				return obj, nil
+			}
+			return nil, fmt.Errorf("unexpected %q", key)
+		}
+		val, quoted, err := p.token()
+		if err != nil {
+			return nil, fmt.Errorf("missing value for %q: %v", key, err)
+		}
+		switch {
+		case quoted:
+			obj[strings.ToLower(key)] = val
+		case val == "{":
+			child, err := p.object(false)
+			if err != nil {
+				return nil, err
+			}
+			obj[strings.ToLower(key)] = child
+		default:
+			return nil, fmt.Errorf("unexpected %q after %q", val, key)
+		}
+	}
+}
+
+// readVDF parses the VDF file at `path`
+func readVDF(path string) (VDF, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseVDF(f)
+}
+
+// SteamRoots returns all existing Steam installation folders for this OS
+//
+// On Linux this includes native, Flatpak and Snap Steam, as well as Windows Steam on drives mounted by WSL
+func SteamRoots() []string {
+	var candidates []string
+	home, _ := os.UserHomeDir()
+	switch runtime.GOOS {
+	case "windows":
+		for _, env := range []string{"ProgramFiles(x86)", "ProgramFiles"} {
+			if dir := os.Getenv(env); dir != "" {
+				candidates = append(candidates, filepath.Join(dir, "Steam"))
+			}
+		}
+		candidates = append(candidates, "C:/Program Files (x86)/Steam")
+	case "darwin":
+		candidates = append(candidates, filepath.Join(home, "Library/Application Support/Steam"))
+	default:
+		if home != "" {
+			candidates = append(candidates,
+				filepath.Join(home, ".steam/steam"),
+				filepath.Join(home, ".local/share/Steam"),
+				filepath.Join(home, ".var/app/com.valvesoftware.Steam/.local/share/Steam"),
+				filepath.Join(home, ".var/app/com.valvesoftware.Steam/data/Steam"),
+				filepath.Join(home, "snap/steam/common/.local/share/Steam"),
+			)
+		}
+		// Windows drives mounted by WSL
+		for _, pattern := range []string{"/mnt/?/Program Files (x86)/Steam", "/mnt/?/Program Files/Steam", "/mnt/?/Steam"} {
+			matches, _ := filepath.Glob(pattern)
+			candidates = append(candidates, matches...)
+		}
+	}
+	var roots []string
+	seen := make(playlist.StringSet)
+	for _, c := range candidates {
+		if !fsutil.DirExists(filepath.Join(c, "steamapps")) {
+			continue
+		}
+		// ~/.steam/steam is usually a symlink to one of the others
+		if real, err := filepath.EvalSymlinks(c); err == nil {
+			c = real
+		}
+		if seen.Contains(c) {
+			continue
+		}
+		seen[c] = struct{}{}
+		roots = append(roots, c)
+	}
+	return roots
+}
+
+// SteamLibraries returns all library folders listed in `root`'s libraryfolders.vdf, including `root` itself
+func SteamLibraries(root string) []string {
+	libs := []string{root}
+	for _, name := range []string{"steamapps/libraryfolders.vdf", "config/libraryfolders.vdf"} {
+		v, err := readVDF(filepath.Join(root, name))
+		if err != nil {
+			log.Debugf("SteamLibraries: %v", err)
+			continue
+		}
+		for _, val := range v.Map("libraryfolders") {
+			switch lib := val.(type) {
+			case string:
+				// Old format, "1" "D:\\SteamLibrary", mixed with other keys such as "contentstatsid"
+				if strings.ContainsAny(lib, `/\`) {
+					libs = append(libs, NewPath(lib))
+				}
+			case VDF:
+				// New format, "1" { "path" "D:\\SteamLibrary" ... }
+				if path := lib.String("path"); path != "" {
+					libs = append(libs, NewPath(path))
+				}
+			}
+		}
+		break
+	}
+	return libs
+}
+
+// FindBeatSaber returns the Beat Saber install folder in Steam library `lib`, if its app manifest exists
+func FindBeatSaber(lib string) (string, bool) {
+	v, err := readVDF(filepath.Join(lib, "steamapps", "appmanifest_"+beatSaberAppID+".acf"))
+	if err != nil {
+		return "", false
+	}
+	installDir := v.Map("AppState").String("installdir")
+	if installDir == "" {
+		return "", false
+	}
+	path := filepath.ToSlash(filepath.Join(lib, "steamapps", "common", installDir))
+	return path, fsutil.DirExists(path)
+}
+
+// DiscoverGame returns all Beat Saber installs found in Steam libraries
+func DiscoverGame() []string {
+	var games []string
+	seen := make(playlist.StringSet)
+	for _, root := range SteamRoots() {
+		for _, lib := range SteamLibraries(root) {
+			game, ok := FindBeatSaber(lib)
+			if !ok {
+				continue
+			}
+			if real, err := filepath.EvalSymlinks(game); err == nil {
+				game = filepath.ToSlash(real)
+			}
+			if seen.Contains(game) {
+				continue
+			}
+			seen[game] = struct{}{}
+			log.Debugf("DiscoverGame: found %s", game)
+			games = append(games, game)
+		}
+	}
+	return games
+}
@@ -0,0 +1,65 @@
+package library
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseVDF(t *testing.T) {
+	v, err := readVDF("../samples/steam/libraryfolders.vdf")
+	if err != nil {
+		t.Fatalf("VDF parse failed: %v", err)
+	}
+	lib := v.Map("libraryfolders").Map("1")
+	if lib.String("path") != `D:\SteamLibrary` {
+		t.Errorf("Expected D:\\SteamLibrary, got %q", lib.String("path"))
+	}
+	if lib.Map("apps").String(beatSaberAppID) == "" {
+		t.Errorf("Expected Beat Saber in apps, got %v", lib.Map("apps"))
+	}
+	v, err = readVDF("../samples/steam/libraryfolders-old.vdf")
+	if err != nil {
+		t.Fatalf("Old VDF parse failed: %v", err)
+	}
+	if v.Map("LibraryFolders").String("2") != `E:\Games\Steam` {
+		t.Errorf("Expected E:\\Games\\Steam, got %v", v.Map("libraryfolders"))
+	}
+}
+
+func TestSteamLibraries(t *testing.T) {
+	root, err := ioutil.TempDir("", "steam")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	lib := filepath.Join(root, "library")
+	for _, dir := range []string{"steamapps", "library/steamapps/common/Beat Saber"} {
+		if err = os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	vdf := "\"libraryfolders\"\n{\n\t\"1\"\n\t{\n\t\t\"path\"\t\t\"" + lib + "\"\n\t}\n}\n"
+	if err = ioutil.WriteFile(filepath.Join(root, "steamapps/libraryfolders.vdf"), []byte(vdf), 0644); err != nil {
+		t.Fatal(err)
+	}
+	acf, err := ioutil.ReadFile("../samples/steam/appmanifest_620980.acf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(filepath.Join(lib, "steamapps/appmanifest_620980.acf"), acf, 0644); err != nil {
+		t.Fatal(err)
+	}
+	libs := SteamLibraries(root)
+	if len(libs) != 2 || libs[1] != lib {
+		t.Fatalf("Expected [%s %s], got %v", root, lib, libs)
+	}
+	if _, ok := FindBeatSaber(root); ok {
+		t.Errorf("Found game in %s without manifest", root)
+	}
+	game, ok := FindBeatSaber(lib)
+	if !ok || game != filepath.ToSlash(filepath.Join(lib, "steamapps/common/Beat Saber")) {
+		t.Errorf("Expected game in %s, got %q", lib, game)
+	}
+}
@@ -0,0 +1,193 @@
+package library
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/cosandr/go-beat-playlist/playlist"
+	log "github.com/sirupsen/logrus"
+)
+
+// ChangeOp is the kind of change found by Refresh
+type ChangeOp int
+
+const (
+	// Added is a new song folder or playlist file
+	Added ChangeOp = iota
+	// Removed is a song folder or playlist file which no longer exists
+	Removed
+	// Modified is a song folder or playlist file which was changed
+	Modified
+)
+
+// String returns the name of the change
+func (op ChangeOp) String() string {
+	switch op {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case Modified:
+		return "modified"
+	}
+	return "unknown"
+}
+
+// Change is a song folder or playlist file which changed since the library last read it
+type Change struct {
+	Op       ChangeOp
+	Path     string
+	Playlist bool
+}
+
+// DefaultWatchInterval is the default time between two checks of Watch
+const DefaultWatchInterval = time.Minute
+
+// stamp is the state of a song folder or playlist file when it was read, it changes with its contents
+type stamp struct {
+	modTime int64
+	size    int64
+}
+
+// folderStamp returns the current stamp of the song folder `path` and all its subfolders, zero if it cannot be read
+func folderStamp(path string) (st stamp) {
+	err := filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		// Folder times change when entries are added, removed or renamed
+		if t := info.ModTime().UnixNano(); t > st.modTime {
+			st.modTime = t
+		}
+		if !info.IsDir() {
+			st.size += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return stamp{}
+	}
+	return
+}
+
+// fileStamp returns the current stamp of the file `path`, zero if it cannot be read
+func fileStamp(path string) stamp {
+	info, err := os.Stat(path)
+	if err != nil {
+		return stamp{}
+	}
+	return stamp{modTime: info.ModTime().UnixNano(), size: info.Size()}
+}
+
+// Refresh updates the library with song folders and playlist files which changed since they were last read
+//
+// Only changed song folders are scanned again, returns the changes found. Unlike Load, unreadable files are
+// skipped instead of failing.
+func (l *Library) Refresh() (changes []Change, err error) {
+	l.refreshMu.Lock()
+	defer l.refreshMu.Unlock()
+	folders, names, err := listAllSongFolders(l.conf.SongFolders())
+	if err != nil {
+		return
+	}
+	files, err := listPlaylistFiles(l.conf.Playlists)
+	if err != nil {
+		return
+	}
+	l.mu.RLock()
+	oldStamps := l.stamps
+	byFolder := make(map[string][]playlist.Song, len(l.folders))
+	for path, songs := range l.folders {
+		byFolder[path] = songs
+	}
+	// Start from the playlists as read, so their songs get the metadata of the songs installed now
+	byPath := make(map[string]playlist.Playlist, len(l.read))
+	for path, p := range l.read {
+		byPath[path] = p
+	}
+	l.mu.RUnlock()
+
+	stamps := make(map[string]stamp, len(folders)+len(files))
+	diff := func(path string, st stamp, isPlaylist bool) bool {
+		stamps[path] = st
+		old, ok := oldStamps[path]
+		if !ok {
+			changes = append(changes, Change{Op: Added, Path: path, Playlist: isPlaylist})
+		} else if old != st {
+			changes = append(changes, Change{Op: Modified, Path: path, Playlist: isPlaylist})
+		} else {
+			return false
+		}
+		return true
+	}
+	var scan []string
+	for _, path := range folders {
+		if diff(path, folderStamp(path), false) {
+			scan = append(scan, path)
+		}
+	}
+	var read []string
+	for _, path := range files {
+		if diff(path, fileStamp(path), true) {
+			read = append(read, path)
+		}
+	}
+	for path := range byFolder {
+		if _, ok := stamps[path]; !ok {
+			changes = append(changes, Change{Op: Removed, Path: path})
+			delete(byFolder, path)
+		}
+	}
+	for path := range byPath {
+		if _, ok := stamps[path]; !ok {
+			changes = append(changes, Change{Op: Removed, Path: path, Playlist: true})
+			delete(byPath, path)
+		}
+	}
+	if len(changes) == 0 {
+		return
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	for i, songs := range scanSongFolders(scan, names, l.workers, nil, l.cache) {
+		byFolder[scan[i]] = songs
+	}
+	if l.cache != nil && len(scan) > 0 {
+		if err := l.cache.save(nil); err != nil {
+			log.Warnf("Cannot save hash cache: %v", err)
+		}
+	}
+	for _, path := range read {
+		if p, ok := readPlaylist(path); ok {
+			byPath[path] = p
+		} else {
+			delete(byPath, path)
+		}
+	}
+	l.update(byFolder, byPath, stamps)
+	return
+}
+
+// Watch calls Refresh every `interval` until `ctx` is done, calling `fn` when something changed or on errors
+//
+// This polls, each Refresh lists every file of every song folder. With thousands of songs keep `interval` at
+// DefaultWatchInterval or longer.
+func (l *Library) Watch(ctx context.Context, interval time.Duration, fn func([]Change, error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			changes, err := l.Refresh()
+			if err != nil || len(changes) > 0 {
+				fn(changes, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package library
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRefresh(t *testing.T) {
+	dir, err := ioutil.TempDir("", "library")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	c := Config{Songs: filepath.Join(dir, "CustomLevels"), Playlists: filepath.Join(dir, "Playlists")}
+	copySample(t, filepath.Join(c.Songs, "a"))
+	if err = os.MkdirAll(c.Playlists, 0755); err != nil {
+		t.Fatal(err)
+	}
+	lib := NewLibrary(c)
+	lib.SetCachePath("")
+	if err = lib.Load(); err != nil {
+		t.Fatalf("Library load failed: %v", err)
+	}
+	refresh := func(want ...Change) {
+		t.Helper()
+		changes, err := lib.Refresh()
+		if err != nil {
+			t.Fatalf("Refresh failed: %v", err)
+		}
+		if len(changes) != len(want) {
+			t.Fatalf("Expected changes %v, got %v", want, changes)
+		}
+		for i := range want {
+			if changes[i] != want[i] {
+				t.Errorf("Expected change %v, got %v", want[i], changes[i])
+			}
+		}
+	}
+	refresh()
+
+	// New song and playlist
+	copySample(t, filepath.Join(c.Songs, "b"))
+	list := c.Playlists + "/list.bplist"
+	data := `{"playlistTitle": "List", "songs": [{"hash": "` + sampleHash + `"}, {"hash": "missing"}]}`
+	if err = ioutil.WriteFile(list, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	refresh(
+		Change{Op: Added, Path: filepath.Join(c.Songs, "b")},
+		Change{Op: Added, Path: list, Playlist: true},
+	)
+	if songs, playlists := lib.Counts(); songs != 2 || playlists != 1 {
+		t.Errorf("Expected 2 songs and 1 playlist, got %d and %d", songs, playlists)
+	}
+	if n := len(lib.Missing()[list].Songs); n != 1 {
+		t.Errorf("Expected 1 missing song, got %d", n)
+	}
+
+	// Changed song, removed song and playlist
+	later := time.Now().Add(time.Minute)
+	if err = os.Chtimes(filepath.Join(c.Songs, "a", "info.dat"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.RemoveAll(filepath.Join(c.Songs, "b")); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Remove(list); err != nil {
+		t.Fatal(err)
+	}
+	refresh(
+		Change{Op: Modified, Path: filepath.Join(c.Songs, "a")},
+		Change{Op: Removed, Path: filepath.Join(c.Songs, "b")},
+		Change{Op: Removed, Path: list, Playlist: true},
+	)
+	if songs, playlists := lib.Counts(); songs != 1 || playlists != 0 {
+		t.Errorf("Expected 1 song and no playlists, got %d and %d", songs, playlists)
+	}
+}
+
+// oggFile returns a stereo 44.1 kHz Ogg Vorbis file lasting `secs`, with `padding` bytes of audio data
+func oggFile(secs int, padding int) []byte {
+	page := func(granule uint64, packet []byte) []byte {
+		head := make([]byte, 27, 28+len(packet))
+		copy(head, "OggS")
+		binary.LittleEndian.PutUint64(head[6:14], granule)
+		binary.LittleEndian.PutUint32(head[14:18], 1)
+		head[26] = 1
+		head = append(head, byte(len(packet)))
+		return append(head, packet...)
+	}
+	vorbis := make([]byte, 30)
+	copy(vorbis, "\x01vorbis")
+	vorbis[11] = 2
+	binary.LittleEndian.PutUint32(vorbis[12:16], 44100)
+	return append(page(0, vorbis), page(uint64(44100*secs), make([]byte, padding))...)
+}
+
+func TestRefreshPlaylistSongs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "library")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	c := Config{Songs: filepath.Join(dir, "CustomLevels"), Playlists: filepath.Join(dir, "Playlists")}
+	song := filepath.Join(c.Songs, "a")
+	copySample(t, song)
+	if err = ioutil.WriteFile(filepath.Join(song, "song.egg"), oggFile(60, 10), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.MkdirAll(c.Playlists, 0755); err != nil {
+		t.Fatal(err)
+	}
+	list := c.Playlists + "/list.bplist"
+	if err = ioutil.WriteFile(list, []byte(`{"playlistTitle": "List", "songs": [{"hash": "`+sampleHash+`"}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	lib := NewLibrary(c)
+	lib.SetCachePath("")
+	if err = lib.Load(); err != nil {
+		t.Fatalf("Library load failed: %v", err)
+	}
+	duration := func() time.Duration {
+		p, _ := lib.Playlist(list)
+		return p.Duration()
+	}
+	if d := duration(); d != time.Minute {
+		t.Fatalf("Expected playlist of 1m, got %s", d)
+	}
+
+	// Playlist songs get the metadata of the changed song, not the one they were first merged with
+	if err = ioutil.WriteFile(filepath.Join(song, "song.egg"), oggFile(120, 20), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if changes, err := lib.Refresh(); err != nil || len(changes) != 1 {
+		t.Fatalf("Expected the song to change, got %v, %v", changes, err)
+	}
+	if d := duration(); d != 2*time.Minute {
+		t.Errorf("Expected playlist of 2m after refresh, got %s", d)
+	}
+
+	// Changes in subfolders are found as well
+	nested := filepath.Join(song, "extra", "more")
+	if err = os.MkdirAll(nested, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = lib.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(filepath.Join(nested, "notes.txt"), []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	changes, err := lib.Refresh()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0] != (Change{Op: Modified, Path: song}) {
+		t.Errorf("Expected the song to be modified, got %v", changes)
+	}
+}
@@ -0,0 +1,303 @@
+package playlist
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// peakWindow is the window in seconds the peak notes per second are counted in
+const peakWindow = 2.0
+
+// MapStats holds what was found by analysing a difficulty file
+type MapStats struct {
+	Bombs int `json:"bombs"`
+	Notes int `json:"notes"`
+	Walls int `json:"walls"`
+	// Duration is the time in seconds until the last note or wall ends
+	Duration float64 `json:"duration"`
+	// AvgNPS is the number of notes per second over the whole duration
+	AvgNPS float64 `json:"avgNps"`
+	// PeakNPS is the highest number of notes per second within any window of peakWindow seconds
+	PeakNPS    float64     `json:"peakNps"`
+	BPMChanges []BPMChange `json:"bpmChanges,omitempty"`
+}
+
+// BPMChange is a change of tempo at a beat
+type BPMChange struct {
+	Beat float64 `json:"beat"`
+	BPM  float64 `json:"bpm"`
+}
+
+// difficultyJSON is the structure of v2, v3 and v4 difficulty files (only relevant bits)
+type difficultyJSON struct {
+	// v2
+	Notes []struct {
+		Time float64 `json:"_time"`
+		Type int     `json:"_type"`
+	} `json:"_notes"`
+	ObstaclesV2 []struct {
+		Time     float64 `json:"_time"`
+		Duration float64 `json:"_duration"`
+	} `json:"_obstacles"`
+	Events []struct {
+		Time       float64 `json:"_time"`
+		Type       int     `json:"_type"`
+		FloatValue float64 `json:"_floatValue"`
+	} `json:"_events"`
+	BPMChanges []bpmChangeV2JSON `json:"_BPMChanges"`
+	CustomData struct {
+		BPMChanges []bpmChangeV2JSON `json:"_BPMChanges"`
+	} `json:"_customData"`
+	// v3 and v4, v4 obstacles keep their duration in obstaclesData
+	ColorNotes []beatJSON `json:"colorNotes"`
+	BombNotes  []beatJSON `json:"bombNotes"`
+	Obstacles  []struct {
+		Beat     float64 `json:"b"`
+		Duration float64 `json:"d"`
+		Index    *int    `json:"i"`
+	} `json:"obstacles"`
+	ObstaclesData []struct {
+		Duration float64 `json:"d"`
+	} `json:"obstaclesData"`
+	BPMEvents []struct {
+		Beat float64 `json:"b"`
+		BPM  float64 `json:"m"`
+	} `json:"bpmEvents"`
+}
+
+// beatJSON is any v3/v4 object, only its beat is used
+type beatJSON struct {
+	Beat float64 `json:"b"`
+}
+
+// bpmChangeV2JSON is a BPM change in v2 custom data
+type bpmChangeV2JSON struct {
+	Time float64 `json:"_time"`
+	BPM  float64 `json:"_BPM"`
+}
+
+// audioDataJSON is the structure of a v4 audio data file (only relevant bits)
+type audioDataJSON struct {
+	Frequency float64 `json:"songFrequency"`
+	BPMData   []struct {
+		StartIndex float64 `json:"si"`
+		EndIndex   float64 `json:"ei"`
+		StartBeat  float64 `json:"sb"`
+		EndBeat    float64 `json:"eb"`
+	} `json:"bpmData"`
+}
+
+// bpmEventType is the v2 event type of official BPM changes
+const bpmEventType = 100
+
+// AnalyzeBeatmap returns the stats of the difficulty file at `path` for a song at `bpm`
+//
+// `changes` are tempo changes defined outside of the file, as v4 maps do in their audio data
+func AnalyzeBeatmap(path string, bpm float64, changes []BPMChange) (st MapStats, err error) {
+	file, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+	var j difficultyJSON
+	if err = json.Unmarshal(bytes.TrimPrefix(file, []byte("\xef\xbb\xbf")), &j); err != nil {
+		err = fmt.Errorf("cannot parse %s: %v", path, err)
+		return
+	}
+	// Tempo changes
+	st.BPMChanges = append(st.BPMChanges, changes...)
+	for _, c := range append(j.BPMChanges, j.CustomData.BPMChanges...) {
+		st.BPMChanges = append(st.BPMChanges, BPMChange{Beat: c.Time, BPM: c.BPM})
+	}
+	for _, e := range j.Events {
+		if e.Type == bpmEventType && e.FloatValue > 0 {
+			st.BPMChanges = append(st.BPMChanges, BPMChange{Beat: e.Time, BPM: e.FloatValue})
+		}
+	}
+	for _, e := range j.BPMEvents {
+		st.BPMChanges = append(st.BPMChanges, BPMChange{Beat: e.Beat, BPM: e.BPM})
+	}
+	sort.SliceStable(st.BPMChanges, func(i, k int) bool {
+		return st.BPMChanges[i].Beat < st.BPMChanges[k].Beat
+	})
+	// Objects, in beats
+	var notes []float64
+	var end float64
+	for _, n := range j.Notes {
+		switch n.Type {
+		case 0, 1:
+			notes = append(notes, n.Time)
+		case 3:
+			st.Bombs++
+		}
+		end = maxFloat(end, n.Time)
+	}
+	for _, n := range j.ColorNotes {
+		notes = append(notes, n.Beat)
+		end = maxFloat(end, n.Beat)
+	}
+	for _, n := range j.BombNotes {
+		st.Bombs++
+		end = maxFloat(end, n.Beat)
+	}
+	for _, o := range j.ObstaclesV2 {
+		st.Walls++
+		end = maxFloat(end, o.Time+o.Duration)
+	}
+	for _, o := range j.Obstacles {
+		st.Walls++
+		d := o.Duration
+		if o.Index != nil && *o.Index >= 0 && *o.Index < len(j.ObstaclesData) {
+			d = j.ObstaclesData[*o.Index].Duration
+		}
+		end = maxFloat(end, o.Beat+d)
+	}
+	st.Notes = len(notes)
+	if bpm <= 0 {
+		return
+	}
+	st.Duration = beatTime(end, bpm, st.BPMChanges)
+	if st.Duration > 0 {
+		st.AvgNPS = float64(st.Notes) / st.Duration
+	}
+	// Peak notes per second in a sliding window
+	times := make([]float64, len(notes))
+	for i, b := range notes {
+		times[i] = beatTime(b, bpm, st.BPMChanges)
+	}
+	sort.Float64s(times)
+	var peak, first int
+	for last := range times {
+		for times[last]-times[first] >= peakWindow {
+			first++
+		}
+		if n := last - first + 1; n > peak {
+			peak = n
+		}
+	}
+	st.PeakNPS = float64(peak) / peakWindow
+	return
+}
+
+// Analyze sets the stats of each of its maps from their difficulty files, and reads its audio file
+//
+// Maps which cannot be read keep their previous stats, the first error is returned
+func (s *Song) Analyze() (err error) {
+	if s.SongFile != "" {
+		audio, errA := ReadOggInfo(s.Path + "/" + s.SongFile)
+		if os.IsNotExist(errA) {
+			log.Debugf("%s has no audio file %s", s.Name, s.SongFile)
+		} else if errA != nil {
+			err = fmt.Errorf("%s analysis failed: %v", s.Name, errA)
+		} else {
+			s.Audio = &audio
+		}
+	}
+	var changes []BPMChange
+	if s.AudioData != "" {
+		var errA error
+		if changes, errA = readAudioBPM(s.Path+"/"+s.AudioData, s.BPM); errA != nil && err == nil {
+			err = fmt.Errorf("%s analysis failed: %v", s.Name, errA)
+		}
+	}
+	for i := range s.Maps {
+		st, errA := AnalyzeBeatmap(s.Path+"/"+s.Maps[i].File, s.BPM, changes)
+		if errA != nil {
+			if err == nil {
+				err = fmt.Errorf("%s analysis failed: %v", s.Name, errA)
+			}
+			continue
+		}
+		s.Maps[i].Stats = &st
+	}
+	return
+}
+
+// StatsByFile returns the stats of all analysed maps, keyed by difficulty file
+func (s *Song) StatsByFile() map[string]MapStats {
+	ret := make(map[string]MapStats)
+	for _, bm := range s.Maps {
+		if bm.Stats != nil {
+			ret[bm.File] = *bm.Stats
+		}
+	}
+	return ret
+}
+
+// SetStats sets the stats of its maps from `stats`, keyed by difficulty file
+//
+// Returns false if any map has no stats in it
+func (s *Song) SetStats(stats map[string]MapStats) bool {
+	ok := true
+	for i := range s.Maps {
+		st, found := stats[s.Maps[i].File]
+		if !found {
+			ok = false
+			continue
+		}
+		s.Maps[i].Stats = &st
+	}
+	return ok
+}
+
+// readAudioBPM returns the tempo changes in the v4 audio data file at `path`
+//
+// Regions with the song's `bpm` are left out
+func readAudioBPM(path string, bpm float64) (changes []BPMChange, err error) {
+	file, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+	var j audioDataJSON
+	if err = json.Unmarshal(bytes.TrimPrefix(file, []byte("\xef\xbb\xbf")), &j); err != nil {
+		return
+	}
+	if j.Frequency <= 0 {
+		return
+	}
+	current := bpm
+	for _, r := range j.BPMData {
+		seconds := (r.EndIndex - r.StartIndex) / j.Frequency
+		if seconds <= 0 {
+			continue
+		}
+		regionBPM := (r.EndBeat - r.StartBeat) / seconds * 60
+		// Beats are rounded, ignore tiny differences
+		if regionBPM-current > 0.1 || current-regionBPM > 0.1 {
+			changes = append(changes, BPMChange{Beat: r.StartBeat, BPM: regionBPM})
+			current = regionBPM
+		}
+	}
+	return
+}
+
+// beatTime returns the time in seconds of `beat`, for a song starting at `bpm` with tempo `changes` sorted by beat
+func beatTime(beat float64, bpm float64, changes []BPMChange) float64 {
+	var seconds, last float64
+	current := bpm
+	for _, c := range changes {
+		if c.Beat > beat {
+			break
+		}
+		if c.BPM <= 0 {
+			continue
+		}
+		seconds += (c.Beat - last) * 60 / current
+		last = c.Beat
+		current = c.BPM
+	}
+	return seconds + (beat-last)*60/current
+}
+
+// maxFloat returns the larger of `a` and `b`
+func maxFloat(a float64, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
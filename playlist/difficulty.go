@@ -0,0 +1,295 @@
+package playlist
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Difficulty is one of the game's five difficulties, ordered from Easy to Expert+
+type Difficulty int
+
+const (
+	// UnknownDifficulty is a difficulty which could not be parsed
+	UnknownDifficulty Difficulty = iota
+	// Easy difficulty
+	Easy
+	// Normal difficulty
+	Normal
+	// Hard difficulty
+	Hard
+	// Expert difficulty
+	Expert
+	// ExpertPlus is Expert+
+	ExpertPlus
+)
+
+// difficultyNames are the info.dat spellings of each difficulty
+var difficultyNames = [...]string{"Unknown", "Easy", "Normal", "Hard", "Expert", "ExpertPlus"}
+
+// Characteristic is the kind of a map, Standard, One Saber, 360 degree and so on
+//
+// Mods add their own characteristics, those are kept as spelled in the map
+type Characteristic string
+
+const (
+	// Standard is the default characteristic
+	Standard Characteristic = "Standard"
+	// OneSaber maps only use the right saber
+	OneSaber Characteristic = "OneSaber"
+	// NoArrows maps only have dot notes
+	NoArrows Characteristic = "NoArrows"
+	// Degree90 maps turn the player within 90 degrees
+	Degree90 Characteristic = "90Degree"
+	// Degree360 maps turn the player all around
+	Degree360 Characteristic = "360Degree"
+	// Lightshow maps have no notes (SongCore)
+	Lightshow Characteristic = "Lightshow"
+	// Lawless maps ignore the usual mapping rules (SongCore)
+	Lawless Characteristic = "Lawless"
+	// Legacy maps were converted from older game versions
+	Legacy Characteristic = "Legacy"
+)
+
+// characteristics are the known characteristics, keyed by normalised name
+var characteristics = map[string]Characteristic{
+	"standard":  Standard,
+	"onesaber":  OneSaber,
+	"noarrows":  NoArrows,
+	"90degree":  Degree90,
+	"degree90":  Degree90,
+	"360degree": Degree360,
+	"degree360": Degree360,
+	"lightshow": Lightshow,
+	"lawless":   Lawless,
+	"legacy":    Legacy,
+}
+
+// ParseDifficulty returns the difficulty named `name` as spelled by any source
+//
+// Accepts info.dat's ExpertPlus, Song Browser's Expert+, BeatSaver's expertPlus and the ranks 1 to 9 info.dat and
+// ScoreSaber use.
+func ParseDifficulty(name string) (Difficulty, error) {
+	key := normaliseName(name)
+	for d := Easy; d <= ExpertPlus; d++ {
+		if key == strings.ToLower(difficultyNames[d]) {
+			return d, nil
+		}
+	}
+	if rank, err := strconv.Atoi(key); err == nil {
+		for d := Easy; d <= ExpertPlus; d++ {
+			if rank == d.Rank() {
+				return d, nil
+			}
+		}
+	}
+	return UnknownDifficulty, fmt.Errorf("unknown difficulty %q", name)
+}
+
+// String returns the info.dat spelling of the difficulty
+func (d Difficulty) String() string {
+	if d < Easy || d > ExpertPlus {
+		return difficultyNames[UnknownDifficulty]
+	}
+	return difficultyNames[d]
+}
+
+// Pretty returns the difficulty as shown in game, Expert+ instead of ExpertPlus
+func (d Difficulty) Pretty() string {
+	if d == ExpertPlus {
+		return "Expert+"
+	}
+	return d.String()
+}
+
+// Rank returns the rank info.dat files store for the difficulty, from 1 (Easy) to 9 (Expert+), 0 if unknown
+func (d Difficulty) Rank() int {
+	if d < Easy || d > ExpertPlus {
+		return 0
+	}
+	return int(d)*2 - 1
+}
+
+// MarshalText returns the info.dat spelling, so difficulties are written as names in JSON
+func (d Difficulty) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText parses any spelling ParseDifficulty accepts
+func (d *Difficulty) UnmarshalText(text []byte) (err error) {
+	*d, err = ParseDifficulty(string(text))
+	return
+}
+
+// ParseCharacteristic returns the characteristic named `name` as spelled by any source
+//
+// Known characteristics are matched regardless of case, with ScoreSaber's Solo prefix removed. Unknown ones are
+// returned as is, an empty name is Standard.
+func ParseCharacteristic(name string) Characteristic {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return Standard
+	}
+	key := normaliseName(name)
+	if c, ok := characteristics[key]; ok {
+		return c
+	}
+	if c, ok := characteristics[strings.TrimPrefix(key, "solo")]; ok {
+		return c
+	}
+	return Characteristic(name)
+}
+
+// ParseScoreSaberDifficulty returns the characteristic and difficulty of a ScoreSaber difficulty string
+//
+// These look like _ExpertPlus_SoloStandard.
+func ParseScoreSaberDifficulty(s string) (c Characteristic, d Difficulty, err error) {
+	parts := strings.SplitN(strings.TrimPrefix(s, "_"), "_", 2)
+	if len(parts) != 2 {
+		return Standard, UnknownDifficulty, fmt.Errorf("invalid ScoreSaber difficulty %q", s)
+	}
+	d, err = ParseDifficulty(parts[0])
+	return ParseCharacteristic(parts[1]), d, err
+}
+
+// normaliseName lowercases `name` and removes separators, Expert+ becomes expertplus
+func normaliseName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = strings.ReplaceAll(name, "+", "plus")
+	return strings.NewReplacer("_", "", "-", "", " ", "").Replace(name)
+}
+
+// DiffMode is how a DiffSelector picks a song's difficulty
+type DiffMode int
+
+const (
+	// ByMax picks the ranked difficulty with the highest value
+	ByMax DiffMode = iota
+	// ByMin picks the ranked difficulty with the lowest value
+	ByMin
+	// ByDifficulty picks the chosen difficulty, of any characteristic
+	ByDifficulty
+)
+
+// DiffSelector picks which ranked map of a song its stars or PP are taken from
+type DiffSelector struct {
+	Mode DiffMode
+	// Difficulty is the difficulty picked by ByDifficulty
+	Difficulty Difficulty
+}
+
+// ParseDiffSelector returns the selector for "max", "min" or a difficulty name as ParseDifficulty accepts it
+func ParseDiffSelector(s string) (DiffSelector, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "max":
+		return DiffSelector{Mode: ByMax}, nil
+	case "min":
+		return DiffSelector{Mode: ByMin}, nil
+	}
+	d, err := ParseDifficulty(s)
+	if err != nil {
+		return DiffSelector{}, fmt.Errorf("expected max, min or a difficulty: %v", err)
+	}
+	return DiffSelector{Mode: ByDifficulty, Difficulty: d}, nil
+}
+
+// String returns max, min or the difficulty's name
+func (sel DiffSelector) String() string {
+	switch sel.Mode {
+	case ByMin:
+		return "min"
+	case ByDifficulty:
+		return sel.Difficulty.String()
+	}
+	return "max"
+}
+
+// pick returns the ranked map `sel` picks by the value `get` returns, nil if there is none
+func (sel DiffSelector) pick(maps []Beatmap, get func(*Beatmap) float64) (ret *Beatmap) {
+	for i := range maps {
+		bm := &maps[i]
+		if !bm.Ranked || (sel.Mode == ByDifficulty && bm.Difficulty != sel.Difficulty) {
+			continue
+		}
+		if ret == nil || (sel.Mode == ByMin && get(bm) < get(ret)) || (sel.Mode != ByMin && get(bm) > get(ret)) {
+			ret = bm
+		}
+	}
+	return
+}
+
+// PPMap returns the ranked map `sel` picks by PP, nil if there is none
+func (s *Song) PPMap(sel DiffSelector) *Beatmap {
+	return sel.pick(s.Maps, func(bm *Beatmap) float64 { return bm.PP })
+}
+
+// StarsMap returns the ranked map `sel` picks by stars, nil if there is none
+func (s *Song) StarsMap(sel DiffSelector) *Beatmap {
+	return sel.pick(s.Maps, func(bm *Beatmap) float64 { return bm.Stars })
+}
+
+// PPFor returns the PP of the ranked map `sel` picks
+//
+// Songs without ranked maps fall back to their own PP, unless a difficulty was chosen.
+func (s *Song) PPFor(sel DiffSelector) float64 {
+	if !s.HasRanked() {
+		if sel.Mode == ByDifficulty {
+			return 0
+		}
+		return s.PP
+	}
+	if bm := s.PPMap(sel); bm != nil {
+		return bm.PP
+	}
+	return 0
+}
+
+// StarsFor returns the stars of the ranked map `sel` picks
+//
+// Songs without ranked maps fall back to their own stars, unless a difficulty was chosen.
+func (s *Song) StarsFor(sel DiffSelector) float64 {
+	if !s.HasRanked() {
+		if sel.Mode == ByDifficulty {
+			return 0
+		}
+		return s.Stars
+	}
+	if bm := s.StarsMap(sel); bm != nil {
+		return bm.Stars
+	}
+	return 0
+}
+
+// Highlight sets `bm` as the only difficulty playlists highlight for the song, none if `bm` is nil
+func (s *Song) Highlight(bm *Beatmap) {
+	s.Highlights = nil
+	if bm != nil {
+		s.Highlights = []Highlight{{Characteristic: bm.Type, Difficulty: bm.Difficulty}}
+	}
+}
+
+// HasRanked returns true if any of its maps is ranked
+func (s *Song) HasRanked() bool {
+	for _, bm := range s.Maps {
+		if bm.Ranked {
+			return true
+		}
+	}
+	return false
+}
+
+// Highlight is a difficulty a playlist points out for a song, PlaylistManager selects it in game
+type Highlight struct {
+	Characteristic Characteristic
+	Difficulty     Difficulty
+}
+
+// FindMap returns its map of characteristic `c` and difficulty `d`, nil if it has none
+func (s *Song) FindMap(c Characteristic, d Difficulty) *Beatmap {
+	for i := range s.Maps {
+		if s.Maps[i].Type == c && s.Maps[i].Difficulty == d {
+			return &s.Maps[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package playlist
+
+import "testing"
+
+func TestParseDifficulty(t *testing.T) {
+	var tests = map[string]Difficulty{
+		"ExpertPlus":  ExpertPlus,
+		"Expert+":     ExpertPlus,
+		"expertPlus":  ExpertPlus,
+		"Expert Plus": ExpertPlus,
+		"9":           ExpertPlus,
+		"easy":        Easy,
+		"Normal":      Normal,
+		"3":           Normal,
+		"HARD":        Hard,
+		"Expert":      Expert,
+	}
+	for name, expected := range tests {
+		d, err := ParseDifficulty(name)
+		if err != nil {
+			t.Errorf("%q: parse failed: %v", name, err)
+		} else if d != expected {
+			t.Errorf("%q: expected %s, got %s", name, expected, d)
+		}
+	}
+	for _, name := range []string{"", "Impossible", "2"} {
+		if d, err := ParseDifficulty(name); err == nil {
+			t.Errorf("%q: expected an error, got %s", name, d)
+		}
+	}
+	for d := Easy; d <= ExpertPlus; d++ {
+		text, _ := d.MarshalText()
+		var parsed Difficulty
+		if err := parsed.UnmarshalText(text); err != nil || parsed != d {
+			t.Errorf("%s: round trip through %q gave %s (%v)", d, text, parsed, err)
+		}
+	}
+}
+
+func TestParseCharacteristic(t *testing.T) {
+	var tests = map[string]Characteristic{
+		"":              Standard,
+		"standard":      Standard,
+		"SoloStandard":  Standard,
+		"OneSaber":      OneSaber,
+		"Solo360Degree": Degree360,
+		"degree90":      Degree90,
+		"Lawless":       Lawless,
+		"CustomMode":    "CustomMode",
+	}
+	for name, expected := range tests {
+		if c := ParseCharacteristic(name); c != expected {
+			t.Errorf("%q: expected %s, got %s", name, expected, c)
+		}
+	}
+	c, d, err := ParseScoreSaberDifficulty("_ExpertPlus_SoloStandard")
+	if err != nil || c != Standard || d != ExpertPlus {
+		t.Errorf("Expected Standard ExpertPlus, got %s %s (%v)", c, d, err)
+	}
+	if _, _, err = ParseScoreSaberDifficulty("ExpertPlus"); err == nil {
+		t.Error("Expected an error for a difficulty without characteristic")
+	}
+}
+
+func TestFindMap(t *testing.T) {
+	s, err := MakeSong("../samples/song-v4/Info.dat")
+	if err != nil {
+		t.Fatalf("Make song failed: %v", err)
+	}
+	if bm := s.FindMap(Lawless, Expert); bm == nil || bm.File != "LawlessExpert.dat" {
+		t.Errorf("Expected Lawless Expert map, got %v", bm)
+	}
+	if bm := s.FindMap(Standard, Hard); bm != nil {
+		t.Errorf("Expected no Standard Hard map, got %s", bm.Debug())
+	}
+}
+
+func TestDiffSelector(t *testing.T) {
+	ranked := func(d Difficulty, pp float64, stars float64) Beatmap {
+		return Beatmap{Difficulty: d, PP: pp, Ranked: true, Stars: stars, Type: Standard}
+	}
+	p := Playlist{Songs: []Song{
+		{Name: "A", Maps: []Beatmap{ranked(Hard, 200, 4), ranked(ExpertPlus, 400, 9)}},
+		{Name: "B", Maps: []Beatmap{ranked(Expert, 300, 7), {Difficulty: ExpertPlus, Type: Standard}}},
+		{Name: "C", PP: 250},
+	}}
+	var tests = []struct {
+		sel      string
+		expected string
+	}{
+		{"max", "ABC"},
+		{"min", "BCA"},
+		{"expert", "BAC"},
+		{"Hard", "ABC"},
+	}
+	for _, tt := range tests {
+		sel, err := ParseDiffSelector(tt.sel)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.sel, err)
+		}
+		sorted := p.Filter(func(*Song) bool { return true })
+		sorted.SortByPP(sel)
+		var order string
+		for _, s := range sorted.Songs {
+			order += s.Name
+		}
+		if order != tt.expected {
+			t.Errorf("%s: expected order %s, got %s", tt.sel, tt.expected, order)
+		}
+	}
+	if _, err := ParseDiffSelector("hardest"); err == nil {
+		t.Error("Expected an error for an invalid selector")
+	}
+	split := p.SplitRanked()
+	if len(split.Songs) != 4 {
+		t.Fatalf("Expected 4 entries, got %d", len(split.Songs))
+	}
+	split.SortByStars(DiffSelector{})
+	if s := split.Songs[0]; s.Name != "A" || s.Stars != 9 || len(s.Maps) != 1 || s.Maps[0].Difficulty != ExpertPlus {
+		t.Errorf("Expected A Expert+ first, got %s", s.Debug())
+	}
+}
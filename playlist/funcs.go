@@ -0,0 +1,260 @@
+package playlist
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// MakePlaylist returns a Playlist from a json file path
+//
+// All fields are kept, along with the file's formatting, so it can be written back as it was
+func MakePlaylist(path string) (p Playlist, err error) {
+	var j PlaylistJSON
+	file, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+	format := detectFormat(file)
+	file = bytes.TrimPrefix(file, []byte("\xef\xbb\xbf"))
+	err = json.Unmarshal(file, &j)
+	if err != nil {
+		return
+	}
+	raw, err := parseRawObject(file)
+	if err != nil {
+		return
+	}
+	var rawSongs []json.RawMessage
+	if v, ok := raw.get("songs"); ok {
+		if err = json.Unmarshal(v, &rawSongs); err != nil {
+			return
+		}
+	}
+	var songs []Song
+	for i, s := range j.Songs {
+		song := Song{
+			Key:        strings.ToLower(playlistKey(s.Key)),
+			Name:       s.Name,
+			Hash:       strings.ToLower(s.Hash),
+			Highlights: parseHighlights(s.Difficulties),
+			LevelID:    s.LevelID,
+		}
+		if song.Hash == "" {
+			song.Hash = LevelHash(s.LevelID)
+		}
+		if song.raw, err = parseRawObject(rawSongs[i]); err != nil {
+			err = fmt.Errorf("cannot parse song %d: %v", i, err)
+			return
+		}
+		songs = append(songs, song)
+	}
+	p = Playlist{
+		Title:       j.Title,
+		Author:      j.Author,
+		Description: j.Description,
+		Image:       j.Image,
+		File:        path,
+		Songs:       songs,
+		raw:         raw,
+		format:      &format,
+	}
+	return
+}
+
+// playlistKey returns the BeatSaver key of a playlist entry, which might be read as float64 instead of string
+func playlistKey(v interface{}) string {
+	switch vv := v.(type) {
+	case float64:
+		return fmt.Sprintf("%.0f", vv)
+	case string:
+		return vv
+	}
+	return ""
+}
+
+// parseHighlights returns the highlighted difficulties of a playlist entry, unknown difficulties are left out
+func parseHighlights(diffs []DifficultyJSON) (highlights []Highlight) {
+	for _, d := range diffs {
+		diff, err := ParseDifficulty(d.Name)
+		if err != nil {
+			log.Debugf("Ignoring highlighted difficulty: %v", err)
+			continue
+		}
+		highlights = append(highlights, Highlight{Characteristic: ParseCharacteristic(d.Characteristic), Difficulty: diff})
+	}
+	return
+}
+
+// MakeSong returns a Song from a info.dat file path
+func MakeSong(infoPath string) (s Song, err error) {
+	s, err = ReadSong(infoPath)
+	if err != nil {
+		return
+	}
+	err = s.CalcHashFrom(infoPath)
+	return
+}
+
+// ReadSong returns a Song from a info.dat file path without calculating its hash
+//
+// Both the v2 and v4 info.dat schemas are understood
+func ReadSong(infoPath string) (s Song, err error) {
+	log.Debugf("ReadSong: read %s", infoPath)
+	file, err := ioutil.ReadFile(infoPath)
+	if err != nil {
+		return
+	}
+	// Some editors write a BOM, which the JSON decoder does not accept
+	file = bytes.TrimPrefix(file, []byte("\xef\xbb\xbf"))
+	var v InfoVersionJSON
+	if err = json.Unmarshal(file, &v); err != nil {
+		err = fmt.Errorf("cannot parse %s: %v", infoPath, err)
+		return
+	}
+	switch {
+	case strings.HasPrefix(v.Version, "4."):
+		s, err = parseInfoV4(file)
+	case v.Version != "" && v.V2Version == "":
+		err = fmt.Errorf("unsupported info.dat version %s", v.Version)
+	default:
+		s, err = parseInfoV2(file)
+	}
+	if err != nil {
+		err = fmt.Errorf("cannot parse %s: %v", infoPath, err)
+		return
+	}
+	s.Path = path.Dir(strings.ReplaceAll(infoPath, "\\", "/"))
+	log.Debugf("ReadSong: output\n%s", s.Debug())
+	return
+}
+
+// parseInfoV2 returns a Song from the contents of a v2 info.dat
+func parseInfoV2(file []byte) (s Song, err error) {
+	var j InfoJSON
+	if err = json.Unmarshal(file, &j); err != nil {
+		return
+	}
+	var maps []Beatmap
+	for _, set := range j.Beatmaps {
+		for _, m := range set.Maps {
+			bm := Beatmap{Type: ParseCharacteristic(set.Type)}
+			bm.File = m.File
+			bm.Difficulty = parseMapDifficulty(m.Difficulty)
+			bm.Label = m.CustomData.Label
+			bm.NJS = m.NJS
+			bm.NJSOffset = m.NJSOffset
+			bm.Rank = m.Rank
+			maps = append(maps, bm)
+		}
+	}
+	var contributors []Contributor
+	for _, c := range j.CustomData.Contributors {
+		contributors = append(contributors, Contributor{Name: c.Name, Role: c.Role})
+	}
+	s = Song{
+		Name:            j.SongName,
+		SubName:         j.SongSubName,
+		Author:          j.SongAuthor,
+		Mapper:          j.Mapper,
+		Maps:            maps,
+		BPM:             j.BPM,
+		Contributors:    contributors,
+		Cover:           j.Cover,
+		Environment:     j.Environment,
+		PreviewDuration: j.PreviewDuration,
+		PreviewStart:    j.PreviewStart,
+		SongFile:        j.SongFile,
+		Version:         j.Version,
+	}
+	return
+}
+
+// parseMapDifficulty returns the difficulty of a map, unknown difficulties are logged
+func parseMapDifficulty(name string) Difficulty {
+	d, err := ParseDifficulty(name)
+	if err != nil {
+		log.Debugf("Cannot parse map: %v", err)
+	}
+	return d
+}
+
+// parseInfoV4 returns a Song from the contents of a v4 Info.dat
+//
+// v4 has no song level mapper, it is made up of the mappers of all difficulties
+func parseInfoV4(file []byte) (s Song, err error) {
+	var j InfoV4JSON
+	if err = json.Unmarshal(file, &j); err != nil {
+		return
+	}
+	var maps []Beatmap
+	var mappers []string
+	seen := make(StringSet)
+	for _, m := range j.Beatmaps {
+		d := parseMapDifficulty(m.Difficulty)
+		maps = append(maps, Beatmap{
+			Difficulty: d,
+			File:       m.File,
+			Label:      m.CustomData.Label,
+			Lightshow:  m.Lightshow,
+			NJS:        m.NJS,
+			NJSOffset:  m.NJSOffset,
+			// v4 has no difficulty rank, it is implied by the difficulty
+			Rank: d.Rank(),
+			Type: ParseCharacteristic(m.Characteristic),
+		})
+		for _, name := range m.Authors.Mappers {
+			if !seen.Contains(name) {
+				seen[name] = struct{}{}
+				mappers = append(mappers, name)
+			}
+		}
+	}
+	var contributors []Contributor
+	for _, c := range j.CustomData.Contributors {
+		contributors = append(contributors, Contributor{Name: c.Name, Role: c.Role})
+	}
+	var environment string
+	if len(j.Environments) > 0 {
+		environment = j.Environments[0]
+	}
+	s = Song{
+		AudioData:       j.Audio.AudioDataFilename,
+		Name:            j.Song.Title,
+		SubName:         j.Song.SubTitle,
+		Author:          j.Song.Author,
+		Mapper:          strings.Join(mappers, ", "),
+		Maps:            maps,
+		BPM:             j.Audio.BPM,
+		Contributors:    contributors,
+		Cover:           j.Cover,
+		Environment:     environment,
+		PreviewDuration: j.Audio.PreviewDuration,
+		PreviewStart:    j.Audio.PreviewStart,
+		SongFile:        j.Audio.SongFilename,
+		Version:         j.Version,
+	}
+	return
+}
+
+// FindInfo returns the path to info.dat, case insensitive search
+func FindInfo(basePath string) (string, error) {
+	var infoPath string
+	err := filepath.Walk(basePath, func(subpath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.ToLower(info.Name()) == "info.dat" {
+			infoPath = subpath
+		}
+		return nil
+	})
+	return infoPath, err
+}
@@ -0,0 +1,129 @@
+package playlist
+
+import "strings"
+
+// Index looks up songs by hash, key, level ID or name in constant time
+//
+// Build it once per load and use it instead of the linear Playlist.Contains and Playlist.SongPath
+type Index struct {
+	byHash  map[string]int
+	byKey   map[string]int
+	byLevel map[string]int
+	byName  map[string][]int
+	songs   []Song
+}
+
+// NewIndex returns an Index of `songs`, the first song wins if a hash or key appears several times
+func NewIndex(songs []Song) *Index {
+	idx := &Index{
+		byHash:  make(map[string]int, len(songs)),
+		byKey:   make(map[string]int, len(songs)),
+		byLevel: make(map[string]int),
+		byName:  make(map[string][]int, len(songs)),
+	}
+	for _, s := range songs {
+		idx.Add(s)
+	}
+	return idx
+}
+
+// Index returns an Index of this playlist's songs
+func (p *Playlist) Index() *Index {
+	return NewIndex(p.Songs)
+}
+
+// Add adds `s` to the index, returns false if a song with the same hash, key or level ID is already in it
+func (idx *Index) Add(s Song) bool {
+	if idx.Contains(&s) {
+		return false
+	}
+	i := len(idx.songs)
+	idx.songs = append(idx.songs, s)
+	if s.Hash != "" {
+		idx.byHash[s.Hash] = i
+	}
+	if s.Key != "" {
+		idx.byKey[s.Key] = i
+	}
+	if s.LevelID != "" {
+		idx.byLevel[strings.ToLower(s.LevelID)] = i
+	}
+	for _, name := range nameKeys(s.Name) {
+		idx.byName[name] = append(idx.byName[name], i)
+	}
+	return true
+}
+
+// Len returns the number of indexed songs
+func (idx *Index) Len() int {
+	return len(idx.songs)
+}
+
+// Find returns the indexed song equal to `s`, matching by hash, then key, then level ID, same as Song.Equals
+func (idx *Index) Find(s *Song) (Song, bool) {
+	if s.Hash != "" {
+		if i, ok := idx.byHash[s.Hash]; ok {
+			return idx.songs[i], true
+		}
+	}
+	if s.Key != "" {
+		if i, ok := idx.byKey[s.Key]; ok {
+			return idx.songs[i], true
+		}
+	}
+	if s.LevelID != "" {
+		if i, ok := idx.byLevel[strings.ToLower(s.LevelID)]; ok {
+			return idx.songs[i], true
+		}
+	}
+	return Song{}, false
+}
+
+// Contains returns true if a song equal to `s` is indexed
+func (idx *Index) Contains(s *Song) bool {
+	_, ok := idx.Find(s)
+	return ok
+}
+
+// SongPath returns the path of the indexed song equal to `s`, if it has one
+func (idx *Index) SongPath(s *Song) string {
+	found, ok := idx.Find(s)
+	if !ok {
+		return ""
+	}
+	return found.Path
+}
+
+// FindByName returns all indexed songs with the same name as `name`
+//
+// Names are compared case insensitively, a trailing " - subname" is ignored on both sides
+func (idx *Index) FindByName(name string) []Song {
+	var ret []Song
+	seen := make(map[int]struct{})
+	for _, n := range nameKeys(name) {
+		for _, i := range idx.byName[n] {
+			if _, ok := seen[i]; ok {
+				continue
+			}
+			seen[i] = struct{}{}
+			ret = append(ret, idx.songs[i])
+		}
+	}
+	return ret
+}
+
+// nameKeys returns the normalized keys `name` is indexed under, the full name and without its " - subname" suffix
+//
+// Song Browser data names songs "name - subname", with a trailing " - " if there is no subname
+func nameKeys(name string) []string {
+	full := strings.ToLower(strings.TrimSpace(name))
+	full = strings.TrimSpace(strings.TrimSuffix(full, " -"))
+	if full == "" {
+		return nil
+	}
+	keys := []string{full}
+	if i := strings.LastIndex(full, " - "); i > 0 {
+		keys = append(keys, strings.TrimSpace(full[:i]))
+	}
+	return keys
+}
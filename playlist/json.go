@@ -0,0 +1,144 @@
+package playlist
+
+// PlaylistJSON is the structure of a playlist JSON or BPLIST
+type PlaylistJSON struct {
+	Title       string     `json:"playlistTitle"`
+	Author      string     `json:"playlistAuthor"`
+	Description string     `json:"playlistDescription,omitempty"`
+	Image       string     `json:"image,omitempty"`
+	Count       int        `json:"playlistSongCount,omitempty"`
+	Songs       []SongJSON `json:"songs"`
+}
+
+// SongJSON is the structure of a song in a playlist JSON
+type SongJSON struct {
+	Key      interface{} `json:"key,omitempty"`
+	Hash     string      `json:"hash"`
+	Name     string      `json:"songName"`
+	Uploader string      `json:"uploader,omitempty"`
+	LevelID  string      `json:"levelid,omitempty"`
+	// Difficulties are highlighted by PlaylistManager
+	Difficulties []DifficultyJSON `json:"difficulties,omitempty"`
+}
+
+// DifficultyJSON is a highlighted difficulty of a song in a playlist JSON
+type DifficultyJSON struct {
+	Characteristic string `json:"characteristic"`
+	Name           string `json:"name"`
+}
+
+// InfoVersionJSON holds the version fields of all info.dat schemas, used to pick the right one
+type InfoVersionJSON struct {
+	Version   string `json:"version"`
+	V2Version string `json:"_version"`
+}
+
+// InfoJSON is the structure of a song's v2 info.dat file (only relevant bits)
+type InfoJSON struct {
+	Version         string           `json:"_version"`
+	SongName        string           `json:"_songName"`
+	SongSubName     string           `json:"_songSubName"`
+	SongAuthor      string           `json:"_songAuthorName"`
+	Mapper          string           `json:"_levelAuthorName"`
+	BPM             float64          `json:"_beatsPerMinute"`
+	PreviewStart    float64          `json:"_previewStartTime"`
+	PreviewDuration float64          `json:"_previewDuration"`
+	SongFile        string           `json:"_songFilename"`
+	Cover           string           `json:"_coverImageFilename"`
+	Environment     string           `json:"_environmentName"`
+	CustomData      InfoCustomJSON   `json:"_customData"`
+	Beatmaps        []BeatmapSetJSON `json:"_difficultyBeatmapSets"`
+}
+
+// InfoCustomJSON is the custom data of a v2 info.dat
+type InfoCustomJSON struct {
+	Contributors []ContributorJSON `json:"_contributors"`
+}
+
+// ContributorJSON is a contributor listed in the custom data of a v2 info.dat
+type ContributorJSON struct {
+	Role string `json:"_role"`
+	Name string `json:"_name"`
+}
+
+// BeatmapSetJSON is the type used to store beatmap sets (types)
+type BeatmapSetJSON struct {
+	Type string        `json:"_beatmapCharacteristicName"`
+	Maps []BeatmapJSON `json:"_difficultyBeatmaps"`
+}
+
+// BeatmapJSON is the type for a mapping of a difficulty
+type BeatmapJSON struct {
+	Difficulty string            `json:"_difficulty"`
+	Rank       int               `json:"_difficultyRank"`
+	File       string            `json:"_beatmapFilename"`
+	NJS        float64           `json:"_noteJumpMovementSpeed"`
+	NJSOffset  float64           `json:"_noteJumpStartBeatOffset"`
+	CustomData BeatmapCustomJSON `json:"_customData"`
+}
+
+// BeatmapCustomJSON is the custom data of a difficulty in a v2 info.dat
+type BeatmapCustomJSON struct {
+	Label string `json:"_difficultyLabel"`
+}
+
+// InfoV4JSON is the structure of a song's v4 Info.dat file (only relevant bits)
+type InfoV4JSON struct {
+	Version      string           `json:"version"`
+	Song         InfoV4SongJSON   `json:"song"`
+	Audio        InfoV4AudioJSON  `json:"audio"`
+	Cover        string           `json:"coverImageFilename"`
+	Environments []string         `json:"environmentNames"`
+	CustomData   InfoV4CustomJSON `json:"customData"`
+	Beatmaps     []BeatmapV4JSON  `json:"difficultyBeatmaps"`
+}
+
+// InfoV4CustomJSON is the custom data of a v4 Info.dat
+type InfoV4CustomJSON struct {
+	Contributors []ContributorV4JSON `json:"contributors"`
+}
+
+// ContributorV4JSON is a contributor listed in the custom data of a v4 Info.dat
+type ContributorV4JSON struct {
+	Role string `json:"role"`
+	Name string `json:"name"`
+}
+
+// InfoV4SongJSON is the song description in a v4 Info.dat
+type InfoV4SongJSON struct {
+	Title    string `json:"title"`
+	SubTitle string `json:"subTitle"`
+	Author   string `json:"author"`
+}
+
+// InfoV4AudioJSON is the audio description in a v4 Info.dat
+type InfoV4AudioJSON struct {
+	SongFilename      string  `json:"songFilename"`
+	AudioDataFilename string  `json:"audioDataFilename"`
+	BPM               float64 `json:"bpm"`
+	PreviewStart      float64 `json:"previewStartTime"`
+	PreviewDuration   float64 `json:"previewDuration"`
+}
+
+// BeatmapV4JSON is the type for a difficulty in a v4 Info.dat, it has its own characteristic
+type BeatmapV4JSON struct {
+	Characteristic string              `json:"characteristic"`
+	Difficulty     string              `json:"difficulty"`
+	Authors        BeatmapAuthorsJSON  `json:"beatmapAuthors"`
+	NJS            float64             `json:"noteJumpMovementSpeed"`
+	NJSOffset      float64             `json:"noteJumpStartBeatOffset"`
+	File           string              `json:"beatmapDataFilename"`
+	Lightshow      string              `json:"lightshowDataFilename"`
+	CustomData     BeatmapV4CustomJSON `json:"customData"`
+}
+
+// BeatmapV4CustomJSON is the custom data of a difficulty in a v4 Info.dat
+type BeatmapV4CustomJSON struct {
+	Label string `json:"difficultyLabel"`
+}
+
+// BeatmapAuthorsJSON lists the mappers and lighters of a v4 difficulty
+type BeatmapAuthorsJSON struct {
+	Mappers  []string `json:"mappers"`
+	Lighters []string `json:"lighters"`
+}
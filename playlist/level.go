@@ -0,0 +1,27 @@
+package playlist
+
+import "strings"
+
+// customLevelPrefix starts the level ID of custom songs, it is followed by their hash
+const customLevelPrefix = "custom_level_"
+
+// LevelHash returns the hash in the level ID of a custom song, empty for official levels
+//
+// SongCore adds a " WIP" suffix to work in progress songs, it is ignored
+func LevelHash(levelID string) string {
+	if !strings.HasPrefix(strings.ToLower(levelID), customLevelPrefix) {
+		return ""
+	}
+	hash := levelID[len(customLevelPrefix):]
+	if i := strings.IndexByte(hash, ' '); i >= 0 {
+		hash = hash[:i]
+	}
+	return strings.ToLower(hash)
+}
+
+// IsBuiltIn returns true if it is an official level, from the soundtrack or a DLC, which is always installed
+//
+// These have a level ID without the custom level prefix, and cannot be downloaded
+func (s *Song) IsBuiltIn() bool {
+	return s.LevelID != "" && !strings.HasPrefix(strings.ToLower(s.LevelID), customLevelPrefix)
+}
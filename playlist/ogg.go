@@ -0,0 +1,151 @@
+package playlist
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+const (
+	// oggHeaderSize is the size of an Ogg page header without its segment table
+	oggHeaderSize = 27
+	// oggTailSize is how much of the end of a file is searched for the last page at first
+	oggTailSize = 64 * 1024
+	// opusRate is the rate Opus granule positions are counted in, whatever the input sample rate
+	opusRate = 48000
+)
+
+// oggCapture is the magic at the start of every Ogg page
+var oggCapture = []byte("OggS")
+
+// AudioInfo is the format and length of a song's audio file
+type AudioInfo struct {
+	Channels   int           `json:"channels"`
+	Duration   time.Duration `json:"duration"`
+	Format     string        `json:"format"`
+	SampleRate int           `json:"sampleRate"`
+}
+
+// oggPage is the header of an Ogg page
+type oggPage struct {
+	granule  uint64
+	serial   uint32
+	segments []byte
+}
+
+// ReadOggInfo returns the format, sample rate, channels and duration of the Ogg Vorbis or Opus file at `path`
+//
+// Only the first page and the end of the file are read, the duration is the granule position of the last page
+func ReadOggInfo(path string) (info AudioInfo, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return
+	}
+	info, err = parseOgg(f, fi.Size())
+	if err != nil {
+		err = fmt.Errorf("%s: %v", path, err)
+	}
+	return
+}
+
+// parseOgg reads the audio info of an Ogg stream of `size` bytes
+func parseOgg(r io.ReaderAt, size int64) (info AudioInfo, err error) {
+	head := make([]byte, oggHeaderSize+255)
+	n, err := r.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		return
+	}
+	first, ok := parseOggPage(head[:n])
+	if !ok {
+		return info, fmt.Errorf("not an Ogg file")
+	}
+	// The first packet holds the codec's identification header
+	var packetSize int
+	for _, s := range first.segments {
+		packetSize += int(s)
+		if s < 255 {
+			break
+		}
+	}
+	packet := make([]byte, packetSize)
+	if _, err = r.ReadAt(packet, int64(oggHeaderSize+len(first.segments))); err != nil {
+		return info, fmt.Errorf("cannot read identification header: %v", err)
+	}
+	var preSkip uint64
+	switch {
+	case len(packet) >= 16 && packet[0] == 1 && string(packet[1:7]) == "vorbis":
+		info.Format = "vorbis"
+		info.Channels = int(packet[11])
+		info.SampleRate = int(binary.LittleEndian.Uint32(packet[12:16]))
+	case len(packet) >= 16 && string(packet[:8]) == "OpusHead":
+		info.Format = "opus"
+		info.Channels = int(packet[9])
+		info.SampleRate = int(binary.LittleEndian.Uint32(packet[12:16]))
+		preSkip = uint64(binary.LittleEndian.Uint16(packet[10:12]))
+	default:
+		return info, fmt.Errorf("unsupported Ogg codec")
+	}
+	rate := uint64(info.SampleRate)
+	if info.Format == "opus" {
+		rate = opusRate
+	}
+	if rate == 0 {
+		return info, fmt.Errorf("invalid sample rate")
+	}
+	granule, err := lastGranule(r, size, first.serial)
+	if err != nil {
+		return
+	}
+	if granule > preSkip {
+		samples := granule - preSkip
+		info.Duration = time.Duration(samples/rate)*time.Second +
+			time.Duration(samples%rate)*time.Second/time.Duration(rate)
+	}
+	return
+}
+
+// lastGranule returns the granule position of the last page of stream `serial`, searching backwards from the end
+func lastGranule(r io.ReaderAt, size int64, serial uint32) (uint64, error) {
+	for window := int64(oggTailSize); ; window *= 2 {
+		if window > size {
+			window = size
+		}
+		buf := make([]byte, window)
+		if _, err := r.ReadAt(buf, size-window); err != nil && err != io.EOF {
+			return 0, err
+		}
+		for i := bytes.LastIndex(buf, oggCapture); i >= 0; i = bytes.LastIndex(buf[:i], oggCapture) {
+			page, ok := parseOggPage(buf[i:])
+			// Pages where no packet ends have no granule position
+			if ok && page.serial == serial && page.granule != ^uint64(0) {
+				return page.granule, nil
+			}
+		}
+		if window == size {
+			return 0, fmt.Errorf("no Ogg page with a granule position found")
+		}
+	}
+}
+
+// parseOggPage parses the page header at the start of `buf`, returns false if it is not a valid header
+func parseOggPage(buf []byte) (page oggPage, ok bool) {
+	if len(buf) < oggHeaderSize || !bytes.Equal(buf[:4], oggCapture) || buf[4] != 0 {
+		return
+	}
+	numSegments := int(buf[26])
+	if len(buf) < oggHeaderSize+numSegments {
+		return
+	}
+	page.granule = binary.LittleEndian.Uint64(buf[6:14])
+	page.serial = binary.LittleEndian.Uint32(buf[14:18])
+	page.segments = buf[oggHeaderSize : oggHeaderSize+numSegments]
+	return page, true
+}
@@ -0,0 +1,148 @@
+package playlist
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// oggPageBytes returns an Ogg page of stream `serial` holding `packet`, checksums are not verified so left empty
+func oggPageBytes(serial uint32, granule uint64, packet []byte) []byte {
+	var segments []byte
+	for n := len(packet); ; n -= 255 {
+		if n < 255 {
+			segments = append(segments, byte(n))
+			break
+		}
+		segments = append(segments, 255)
+	}
+	head := make([]byte, oggHeaderSize)
+	copy(head, oggCapture)
+	binary.LittleEndian.PutUint64(head[6:14], granule)
+	binary.LittleEndian.PutUint32(head[14:18], serial)
+	head[26] = byte(len(segments))
+	return append(append(head, segments...), packet...)
+}
+
+func TestParseOgg(t *testing.T) {
+	vorbis := make([]byte, 30)
+	copy(vorbis, "\x01vorbis")
+	vorbis[11] = 2
+	binary.LittleEndian.PutUint32(vorbis[12:16], 44100)
+	opus := make([]byte, 19)
+	copy(opus, "OpusHead")
+	opus[9] = 2
+	binary.LittleEndian.PutUint16(opus[10:12], 312)
+	binary.LittleEndian.PutUint32(opus[12:16], 44100)
+
+	var tests = []struct {
+		name     string
+		file     []byte
+		expected AudioInfo
+	}{
+		{
+			name: "vorbis",
+			file: bytes.Join([][]byte{
+				oggPageBytes(1, 0, vorbis),
+				oggPageBytes(1, 44100*60, make([]byte, 4000)),
+				// Other streams and pages without a granule position are skipped
+				oggPageBytes(1, 44100*150+22050, make([]byte, 600)),
+				oggPageBytes(2, 44100*300, nil),
+				oggPageBytes(1, ^uint64(0), make([]byte, 10)),
+			}, nil),
+			expected: AudioInfo{Channels: 2, Duration: 150*time.Second + 500*time.Millisecond, Format: "vorbis",
+				SampleRate: 44100},
+		},
+		{
+			name: "opus",
+			file: bytes.Join([][]byte{
+				oggPageBytes(7, 0, opus),
+				oggPageBytes(7, 48000*90+312, make([]byte, 100)),
+			}, nil),
+			expected: AudioInfo{Channels: 2, Duration: 90 * time.Second, Format: "opus", SampleRate: 44100},
+		},
+	}
+	for _, tt := range tests {
+		info, err := parseOgg(bytes.NewReader(tt.file), int64(len(tt.file)))
+		if err != nil {
+			t.Errorf("%s: parse failed: %v", tt.name, err)
+		} else if info != tt.expected {
+			t.Errorf("%s: expected %+v, got %+v", tt.name, tt.expected, info)
+		}
+	}
+	bad := []byte("RIFF not an ogg file at all, just some bytes")
+	if _, err := parseOgg(bytes.NewReader(bad), int64(len(bad))); err == nil {
+		t.Error("Expected an error for a file which is not Ogg")
+	}
+}
+
+func TestPlaylistDuration(t *testing.T) {
+	p := Playlist{Title: "Test", Songs: []Song{
+		{Name: "First", Hash: "a", Audio: &AudioInfo{Duration: 45*time.Minute + 30*time.Second}},
+		{Name: "Second", Hash: "b", Audio: &AudioInfo{Duration: 16*time.Minute + 33*time.Second + 400*time.Millisecond}},
+		{Name: "Unknown", Hash: "c"},
+	}}
+	expected := "Test\n--- 3 SONGS, 1:02:03 ---\nFirst [a] 45:30\nSecond [b] 16:33\nUnknown [c]\n"
+	if s := p.String(); s != expected {
+		t.Errorf("Expected %q, got %q", expected, s)
+	}
+}
+
+func TestInstalledPlaylistDuration(t *testing.T) {
+	dir, err := ioutil.TempDir("", "playlist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files, err := ioutil.ReadDir("../samples/song-nightraid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		data, err := ioutil.ReadFile(filepath.Join("../samples/song-nightraid", f.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = ioutil.WriteFile(filepath.Join(dir, f.Name()), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	vorbis := make([]byte, 30)
+	copy(vorbis, "\x01vorbis")
+	vorbis[11] = 2
+	binary.LittleEndian.PutUint32(vorbis[12:16], 44100)
+	audio := bytes.Join([][]byte{oggPageBytes(1, 0, vorbis), oggPageBytes(1, 44100*191, make([]byte, 100))}, nil)
+	if err = ioutil.WriteFile(filepath.Join(dir, "song.egg"), audio, 0644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := MakeSong(filepath.Join(dir, "info.dat"))
+	if err != nil {
+		t.Fatalf("Make song failed: %v", err)
+	}
+	s.Path = dir
+	if err = s.Analyze(); err != nil {
+		t.Fatalf("Analysis failed: %v", err)
+	}
+
+	p, err := MakePlaylist("../samples/json/playlist-custom.bplist")
+	if err != nil {
+		t.Fatalf("Playlist JSON parse failed: %v", err)
+	}
+	p.Installed(NewIndex([]Song{s}))
+	out := p.String()
+	if !strings.Contains(out, fmt.Sprintf("--- %d SONGS, 3:11 ---\n", len(p.Songs))) {
+		t.Errorf("Expected total length 3:11, got\n%s", out)
+	}
+	if !strings.Contains(out, "\nNight Raid [1a2b] 3:11 (256 BPM, BigMirrorEnvironment)\n") {
+		t.Errorf("Expected song length 3:11, got\n%s", out)
+	}
+	if bm := p.Songs[0].Maps[0]; bm.Stats == nil || bm.Stats.Notes != 1343 {
+		t.Errorf("Expected analysis of installed song, got %s", bm.Debug())
+	}
+}
@@ -0,0 +1,747 @@
+// Package playlist is the model for Beat Saber playlists and songs
+//
+// It reads and writes playlist files (.bplist/.json) and reads songs from their info.dat
+package playlist
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Matches all invalid NTFS characters
+var reInvalid *regexp.Regexp = regexp.MustCompile(`[<>:"\/\\|?*\n]+`)
+
+// Matches playlist file extensions
+var reExt *regexp.Regexp = regexp.MustCompile(`(\.json$|\.bplist$)`)
+
+// IsPlaylistFile returns true if `name` has a playlist file extension
+func IsPlaylistFile(name string) bool {
+	return reExt.MatchString(name)
+}
+
+// BackupPath returns the path a playlist at `path` is renamed to when backing it up
+func BackupPath(path string) string {
+	return reExt.ReplaceAllString(path, ".bak")
+}
+
+// Playlist holds the filename, raw JSON content and list of songs
+type Playlist struct {
+	Author      string
+	Description string
+	File        string
+	Image       string
+	Songs       []Song
+	Title       string
+	// raw holds all fields of the file it was read from, written back as they were unless changed
+	raw    rawObject
+	format *jsonFormat
+}
+
+// String returns playlist title and its songs
+func (p *Playlist) String() string {
+	var ret string
+	if total := p.Duration(); total > 0 {
+		ret += fmt.Sprintf("%s\n--- %d SONGS, %s ---\n", p.Title, len(p.Songs), FormatDuration(total))
+	} else {
+		ret += fmt.Sprintf("%s\n--- %d SONGS ---\n", p.Title, len(p.Songs))
+	}
+	for _, s := range p.Songs {
+		ret += s.String() + "\n"
+	}
+	return ret
+}
+
+// Duration returns the total length of all songs with a known length
+func (p *Playlist) Duration() (total time.Duration) {
+	for _, s := range p.Songs {
+		total += s.Duration()
+	}
+	return
+}
+
+// Debug returns all playlist and song fields
+func (p *Playlist) Debug() string {
+	var ret string
+	ret += fmt.Sprintf("Title: %s, Author: %s\nFile: %s\n--- %d SONGS ---\n", p.Title, p.Author, p.File, len(p.Songs))
+	for _, s := range p.Songs {
+		ret += s.Debug()
+	}
+	return ret
+}
+
+// Contains returns true if Song is in it
+func (p *Playlist) Contains(comp Song) bool {
+	for _, s := range p.Songs {
+		if s.Equals(&comp) {
+			return true
+		}
+	}
+	return false
+}
+
+// SongPath returns the requested song's path, if it exists
+func (p *Playlist) SongPath(comp Song) string {
+	for _, s := range p.Songs {
+		// Ignore if we have no path or if both key and hash are missing
+		if s.Path == "" || (s.Hash == "" && s.Key == "" && s.LevelID == "") {
+			continue
+		}
+		if s.Equals(&comp) {
+			return s.Path
+		}
+	}
+	return ""
+}
+
+// Installed sets the file path, info.dat metadata and analysis of all its songs, if they are present in `installed`
+//
+// Playlist entries keep their own fields, so they are written back unchanged
+func (p *Playlist) Installed(installed *Index) {
+	var newSongs []Song
+	for _, s := range p.Songs {
+		newSong := s
+		found, ok := installed.Find(&newSong)
+		if ok {
+			newSong = newSong.Merge(&found)
+		}
+		newSong.Path = found.Path
+		newSong.Folder = found.Folder
+		newSongs = append(newSongs, newSong)
+	}
+	p.Songs = newSongs
+}
+
+// ToJSON returns a JSON representation of the Playlist
+//
+// Playlists read from a file keep its formatting and all fields we do not use, only changed values are written
+func (p *Playlist) ToJSON() []byte {
+	data, err := p.encode()
+	if err != nil {
+		fmt.Println(err)
+	}
+	return data
+}
+
+// encode returns the playlist file contents
+func (p *Playlist) encode() ([]byte, error) {
+	var elems []rawObject
+	for i := range p.Songs {
+		o, err := p.Songs[i].encode()
+		if err != nil {
+			return nil, fmt.Errorf("cannot encode %s: %v", p.Songs[i].Name, err)
+		}
+		elems = append(elems, o)
+	}
+	o := p.raw.clone()
+	old, _ := o.get("songs")
+	songs := newRawArray(elems, old)
+	fields := []struct {
+		key       string
+		value     interface{}
+		omitEmpty bool
+	}{
+		{"playlistTitle", p.Title, false},
+		{"playlistAuthor", p.Author, false},
+		{"playlistDescription", p.Description, true},
+		{"image", p.Image, true},
+		{"playlistSongCount", len(p.Songs), true},
+		{"songs", songs, false},
+	}
+	for _, f := range fields {
+		// Files without a song count are left without one
+		if _, ok := o.get(f.key); !ok && p.raw.src != nil && f.key == "playlistSongCount" {
+			continue
+		}
+		if err := o.set(f.key, f.value, f.omitEmpty, nil); err != nil {
+			return nil, err
+		}
+	}
+	format := defaultFormat
+	if p.format != nil {
+		format = *p.format
+	}
+	return format.write(o)
+}
+
+// encode returns its playlist entry
+//
+// Keys and hashes are compared regardless of case and highlights by meaning, so their spelling in the file is kept
+func (s *Song) encode() (o rawObject, err error) {
+	o = s.raw.clone()
+	var highlights []DifficultyJSON
+	for _, h := range s.Highlights {
+		highlights = append(highlights, DifficultyJSON{
+			Characteristic: string(h.Characteristic),
+			Name:           h.Difficulty.String(),
+		})
+	}
+	hash := s.Hash
+	// Entries with only a custom level ID keep it that way
+	if _, ok := o.get("hash"); !ok && hash == LevelHash(s.LevelID) {
+		hash = ""
+	}
+	fields := []struct {
+		key       string
+		value     interface{}
+		omitEmpty bool
+		same      func(json.RawMessage) bool
+	}{
+		{"key", s.Key, true, func(old json.RawMessage) bool {
+			var v interface{}
+			return json.Unmarshal(old, &v) == nil && strings.EqualFold(playlistKey(v), s.Key)
+		}},
+		// Official levels have no hash
+		{"hash", hash, true, func(old json.RawMessage) bool {
+			var v string
+			return json.Unmarshal(old, &v) == nil && strings.EqualFold(v, s.Hash)
+		}},
+		{"levelid", s.LevelID, true, func(old json.RawMessage) bool {
+			var v string
+			return json.Unmarshal(old, &v) == nil && strings.EqualFold(v, s.LevelID)
+		}},
+		{"songName", s.Name, false, nil},
+		{"difficulties", highlights, true, func(old json.RawMessage) bool {
+			var v []DifficultyJSON
+			return json.Unmarshal(old, &v) == nil && reflect.DeepEqual(parseHighlights(v), s.Highlights)
+		}},
+	}
+	for _, f := range fields {
+		if err = o.set(f.key, f.value, f.omitEmpty, f.same); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// Merge returns a new playlist merged with the argument playlist
+func (p *Playlist) Merge(op *Playlist) Playlist {
+	var songs []Song
+	// Keep all songs in this playlist
+	songs = append(songs, p.Songs...)
+	// Add songs only in other playlist
+	idx := p.Index()
+	for _, s := range op.Songs {
+		if !idx.Contains(&s) {
+			songs = append(songs, s)
+		}
+	}
+	ret := *p
+	ret.Songs = songs
+	return ret
+}
+
+// SortByPP sorts this playlist by the PP of the difficulty `sel` picks of each song, in descending order
+func (p *Playlist) SortByPP(sel DiffSelector) {
+	sort.SliceStable(p.Songs, func(i, j int) bool {
+		return p.Songs[i].PPFor(sel) > p.Songs[j].PPFor(sel)
+	})
+}
+
+// SortByStars sorts this playlist by the stars of the difficulty `sel` picks of each song, in descending order
+func (p *Playlist) SortByStars(sel DiffSelector) {
+	sort.SliceStable(p.Songs, func(i, j int) bool {
+		return p.Songs[i].StarsFor(sel) > p.Songs[j].StarsFor(sel)
+	})
+}
+
+// SplitRanked returns a new playlist with one entry per ranked difficulty of each song
+//
+// Each entry only has its ranked map, and that map's stars and PP. Songs without ranked maps are kept as they are.
+func (p *Playlist) SplitRanked() Playlist {
+	ret := *p
+	ret.Songs = nil
+	for _, s := range p.Songs {
+		var found bool
+		for _, bm := range s.Maps {
+			if !bm.Ranked {
+				continue
+			}
+			found = true
+			entry := s
+			entry.Maps = []Beatmap{bm}
+			entry.PP = bm.PP
+			entry.Stars = bm.Stars
+			ret.Songs = append(ret.Songs, entry)
+		}
+		if !found {
+			ret.Songs = append(ret.Songs, s)
+		}
+	}
+	return ret
+}
+
+// SortByBPM sorts this playlist by BPM in descending order
+func (p *Playlist) SortByBPM() {
+	sort.SliceStable(p.Songs, func(i, j int) bool {
+		return p.Songs[i].BPM > p.Songs[j].BPM
+	})
+}
+
+// SortByNJS sorts this playlist by the highest NJS of each song in descending order
+func (p *Playlist) SortByNJS() {
+	sort.SliceStable(p.Songs, func(i, j int) bool {
+		return p.Songs[i].MaxNJS() > p.Songs[j].MaxNJS()
+	})
+}
+
+// Filter returns a new playlist with the songs `keep` returns true for
+func (p *Playlist) Filter(keep func(s *Song) bool) Playlist {
+	ret := *p
+	ret.Songs = nil
+	for i := range p.Songs {
+		if keep(&p.Songs[i]) {
+			ret.Songs = append(ret.Songs, p.Songs[i])
+		}
+	}
+	return ret
+}
+
+// Song holds information about each song
+type Song struct {
+	// Audio is set once the audio file was read
+	Audio *AudioInfo
+	// AudioData is the audio metadata file of v4 maps, included in the hash
+	AudioData    string
+	Author       string
+	BPM          float64
+	Contributors []Contributor
+	Cover        string
+	Environment  string
+	// Folder is the name of the song folder it is installed in, such as Custom Levels or a SongCore folder
+	Folder string
+	Hash   string
+	// Highlights are the difficulties its playlist entry points out
+	Highlights []Highlight
+	Key        string
+	// LevelID is the game's ID of the song, official levels only have this one
+	LevelID string
+	Mapper  string
+	Maps    []Beatmap
+	Name    string
+	Path    string
+	PP      float64
+	// PreviewDuration and PreviewStart are in seconds
+	PreviewDuration float64
+	PreviewStart    float64
+	SongFile        string
+	Stars           float64
+	SubName         string
+	URL             string
+	// Version is the info.dat schema version
+	Version string
+	// raw holds all fields of its playlist entry, written back as they were unless changed
+	raw rawObject
+}
+
+// Contributor is someone credited in a song's info.dat
+type Contributor struct {
+	Name string
+	Role string
+}
+
+// Equals returns true if the key, hash or level ID matches
+func (s *Song) Equals(other *Song) bool {
+	if s.Hash != "" && s.Hash == other.Hash {
+		return true
+	} else if s.Key != "" && s.Key == other.Key {
+		return true
+	} else if s.LevelID != "" && strings.EqualFold(s.LevelID, other.LevelID) {
+		return true
+	}
+	return false
+}
+
+// Merge returns a new song merged with the argument song
+//
+// Prioritizes self, that is, only adds missing fields.
+//
+// BTW, I know this is awful
+func (s *Song) Merge(os *Song) Song {
+	// Start from self so info.dat metadata is kept
+	retSong := *s
+	if len(s.Path) == 0 {
+		retSong.Path = os.Path
+		retSong.Folder = os.Folder
+	} else {
+		retSong.Path = s.Path
+		retSong.Folder = s.Folder
+	}
+
+	if len(s.Key) == 0 {
+		retSong.Key = os.Key
+	} else {
+		retSong.Key = s.Key
+	}
+
+	if len(s.Hash) == 0 {
+		retSong.Hash = os.Hash
+	} else {
+		retSong.Hash = s.Hash
+	}
+
+	if len(s.Name) == 0 {
+		retSong.Name = os.Name
+	} else {
+		retSong.Name = s.Name
+	}
+
+	if len(s.Author) == 0 {
+		retSong.Author = os.Author
+	} else {
+		retSong.Author = s.Author
+	}
+
+	if len(s.Mapper) == 0 {
+		retSong.Mapper = os.Mapper
+	} else {
+		retSong.Mapper = s.Mapper
+	}
+
+	if s.PP == 0 {
+		retSong.PP = os.PP
+	} else {
+		retSong.PP = s.PP
+	}
+
+	if s.Stars == 0 {
+		retSong.Stars = os.Stars
+	} else {
+		retSong.Stars = s.Stars
+	}
+
+	if len(s.Maps) == 0 {
+		retSong.Maps = os.Maps
+	}
+
+	if len(s.URL) == 0 {
+		retSong.URL = os.URL
+	} else {
+		retSong.URL = s.URL
+	}
+
+	if retSong.BPM == 0 {
+		retSong.BPM = os.BPM
+	}
+	if len(retSong.SubName) == 0 {
+		retSong.SubName = os.SubName
+	}
+	if len(retSong.Environment) == 0 {
+		retSong.Environment = os.Environment
+	}
+	if len(retSong.Contributors) == 0 {
+		retSong.Contributors = os.Contributors
+	}
+	if len(retSong.Version) == 0 {
+		retSong.Version = os.Version
+		retSong.AudioData = os.AudioData
+	}
+	if len(retSong.SongFile) == 0 {
+		retSong.SongFile = os.SongFile
+	}
+	if len(retSong.Cover) == 0 {
+		retSong.Cover = os.Cover
+	}
+	if retSong.Audio == nil {
+		retSong.Audio = os.Audio
+	}
+	if retSong.PreviewDuration == 0 {
+		retSong.PreviewStart = os.PreviewStart
+		retSong.PreviewDuration = os.PreviewDuration
+	}
+	if len(s.Maps) > 0 {
+		retSong.Maps = mergeMaps(s.Maps, os.Maps)
+	}
+
+	return retSong
+}
+
+// mergeMaps returns a copy of `maps`, taking info.dat fields and stats missing from each map from the same map in `other`
+func mergeMaps(maps []Beatmap, other []Beatmap) []Beatmap {
+	ret := make([]Beatmap, len(maps))
+	copy(ret, maps)
+	for i := range ret {
+		bm := &ret[i]
+		for _, o := range other {
+			if o.Type != bm.Type || o.Difficulty != bm.Difficulty {
+				continue
+			}
+			if len(bm.File) == 0 {
+				bm.File = o.File
+				bm.Lightshow = o.Lightshow
+			}
+			if len(bm.Label) == 0 {
+				bm.Label = o.Label
+			}
+			if bm.NJS == 0 {
+				bm.NJS = o.NJS
+				bm.NJSOffset = o.NJSOffset
+			}
+			if bm.Rank == 0 {
+				bm.Rank = o.Rank
+			}
+			if bm.Stats == nil {
+				bm.Stats = o.Stats
+			}
+			break
+		}
+	}
+	return ret
+}
+
+// CalcHash calculates this song's hash using its Path
+//
+// song.Hash must end with a trailing slash
+func (s *Song) CalcHash() (err error) {
+	// sha1 hash of (info.dat contents + contents of diff.dat files in order listed in info.dat)
+	infoPath, err := FindInfo(s.Path)
+	if err != nil {
+		log.Debugf("base: %s, info: %s, err: %v", s.Path, infoPath, err)
+		return
+	}
+	return s.CalcHashFrom(infoPath)
+}
+
+// CalcHashFrom calculates this song's hash from the info.dat at `infoPath` and its difficulty files
+//
+// Files are streamed through the hash one at a time instead of being read into memory
+func (s *Song) CalcHashFrom(infoPath string) (err error) {
+	h := sha1.New()
+	for _, f := range s.hashFiles(infoPath) {
+		if err = hashFile(h, f); err != nil {
+			err = fmt.Errorf("%s hash failed: %v", s.Name, err)
+			return
+		}
+	}
+	s.Hash = fmt.Sprintf("%x", h.Sum(nil))
+	return
+}
+
+// hashFiles returns the files making up this song's hash in order, the same as SongCore's GetCustomLevelHash
+//
+// v2 songs hash info.dat, then each difficulty file in the order info.dat lists them. v4 songs hash Info.dat,
+// then the audio data file, then the beatmap file and the lightshow file of each difficulty in Info.dat order.
+// A file listed more than once, usually a lightshow shared by several difficulties, is only hashed the first time.
+func (s *Song) hashFiles(infoPath string) []string {
+	var files = []string{infoPath}
+	if !s.IsV4() {
+		for _, bm := range s.Maps {
+			files = append(files, s.Path+"/"+bm.File)
+		}
+		return files
+	}
+	seen := make(StringSet)
+	add := func(name string) {
+		if name == "" || seen.Contains(name) {
+			return
+		}
+		seen[name] = struct{}{}
+		files = append(files, s.Path+"/"+name)
+	}
+	add(s.AudioData)
+	for _, bm := range s.Maps {
+		add(bm.File)
+		add(bm.Lightshow)
+	}
+	return files
+}
+
+// IsV4 returns true if this song was read from a v4 Info.dat
+func (s *Song) IsV4() bool {
+	return strings.HasPrefix(s.Version, "4.")
+}
+
+// hashFile copies the contents of the file at `path` into `h`
+func hashFile(h hash.Hash, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(h, f)
+	return err
+}
+
+// String returns a string representation of the song
+func (s *Song) String() string {
+	var ret string
+	if len(s.Name) > 0 {
+		ret += s.Name
+	} else {
+		ret += "MISSING"
+	}
+	if len(s.SubName) > 0 {
+		ret += " - " + s.SubName
+	}
+	if len(s.Key) > 0 {
+		ret += fmt.Sprintf(" [%s]", s.Key)
+	} else if len(s.Hash) > 0 {
+		ret += fmt.Sprintf(" [%s]", s.Hash)
+	} else if len(s.LevelID) > 0 {
+		ret += fmt.Sprintf(" [%s]", s.LevelID)
+	}
+	if d := s.Duration(); d > 0 {
+		ret += " " + FormatDuration(d)
+	}
+	if s.BPM > 0 && len(s.Environment) > 0 {
+		ret += fmt.Sprintf(" (%g BPM, %s)", s.BPM, s.Environment)
+	} else if s.BPM > 0 {
+		ret += fmt.Sprintf(" (%g BPM)", s.BPM)
+	}
+	return ret
+}
+
+// Debug returns a string with all values in song
+func (s *Song) Debug() string {
+	var ret string
+	ret += fmt.Sprintf("Path: %s, Folder: %s, URL: %s\n", s.Path, s.Folder, s.URL)
+	ret += fmt.Sprintf("Name: %s, Aut: %s, Mapper: %s\n", s.Name, s.Author, s.Mapper)
+	ret += fmt.Sprintf("Key: %s, Hash: %s, Level: %s, PP: %.2f, Stars: %.2f\n", s.Key, s.Hash, s.LevelID, s.PP, s.Stars)
+	ret += fmt.Sprintf("SubName: %s, BPM: %g, Environment: %s, Version: %s\n", s.SubName, s.BPM, s.Environment, s.Version)
+	ret += fmt.Sprintf("Song: %s, Cover: %s, Preview: %gs+%gs\n", s.SongFile, s.Cover, s.PreviewStart, s.PreviewDuration)
+	if s.Audio != nil {
+		ret += fmt.Sprintf("Audio: %s, %d Hz, %d channels, %s\n", s.Audio.Format, s.Audio.SampleRate, s.Audio.Channels,
+			FormatDuration(s.Audio.Duration))
+	}
+	if len(s.Contributors) > 0 {
+		ret += "Contributors: "
+		for _, c := range s.Contributors {
+			ret += fmt.Sprintf("[%s: %s] ", c.Role, c.Name)
+		}
+		ret += "\n"
+	}
+	ret += "Beatmaps: "
+	for _, m := range s.Maps {
+		ret += "[" + m.Debug() + "] "
+	}
+	return ret
+}
+
+// Duration returns the length of its audio, zero if unknown
+func (s *Song) Duration() time.Duration {
+	if s.Audio == nil {
+		return 0
+	}
+	return s.Audio.Duration
+}
+
+// FormatDuration returns `d` as m:ss, or h:mm:ss if it is an hour or longer
+func FormatDuration(d time.Duration) string {
+	secs := int64(d.Round(time.Second) / time.Second)
+	if secs >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", secs/3600, secs/60%60, secs%60)
+	}
+	return fmt.Sprintf("%d:%02d", secs/60, secs%60)
+}
+
+// MaxNJS returns the highest note jump speed of all its maps
+func (s *Song) MaxNJS() (njs float64) {
+	for _, bm := range s.Maps {
+		if bm.NJS > njs {
+			njs = bm.NJS
+		}
+	}
+	return
+}
+
+// DirName returns this song's directory name
+func (s *Song) DirName() string {
+	var ret string
+	var paran bool
+	if len(s.Key) > 0 {
+		ret = fmt.Sprintf("%s (%s", s.Key, s.Name)
+		paran = true
+	} else {
+		ret = s.Name
+	}
+	if len(s.Author) > 0 {
+		ret += fmt.Sprintf(" - %s", s.Author)
+	} else if len(s.Mapper) > 0 {
+		ret += fmt.Sprintf(" - %s", s.Mapper)
+	}
+	if paran {
+		ret += ")"
+	}
+	// Strip invalid NTFS characters
+	ret = reInvalid.ReplaceAllString(ret, "")
+	// Remove trailing space
+	ret = strings.TrimSuffix(ret, " ")
+	return ret
+}
+
+// Beatmap holds information about a song's map, its difficulty, path to the map file and type (standard, 360, lightshow)
+type Beatmap struct {
+	Difficulty Difficulty
+	File       string
+	// Label is the custom difficulty name shown in game, if any
+	Label string
+	// Lightshow is the lightshow file of v4 maps, which may be shared by several difficulties
+	Lightshow string
+	// NJS is the note jump speed, NJSOffset the note jump start beat offset
+	NJS       float64
+	NJSOffset float64
+	// PP is the ScoreSaber PP of a full combo, Stars its star difficulty, both zero if not ranked
+	PP float64
+	// Rank orders difficulties, from 1 (Easy) to 9 (Expert+)
+	Rank   int
+	Ranked bool
+	Stars  float64
+	// Stats is set once the difficulty file was analysed
+	Stats *MapStats
+	Type  Characteristic
+}
+
+// String returns a pretty type: difficulty string
+func (bm *Beatmap) String() string {
+	ret := fmt.Sprintf("\n%s: %s", bm.Type, bm.Difficulty.Pretty())
+	if len(bm.Label) > 0 {
+		ret += fmt.Sprintf(" (%s)", bm.Label)
+	}
+	if bm.Ranked {
+		ret += fmt.Sprintf(", %.2f stars, %.2f PP", bm.Stars, bm.PP)
+	}
+	if bm.NJS > 0 {
+		ret += fmt.Sprintf(", %g NJS", bm.NJS)
+	}
+	if bm.Stats != nil {
+		ret += fmt.Sprintf(", %d notes, %.2f NPS", bm.Stats.Notes, bm.Stats.AvgNPS)
+	}
+	return ret
+}
+
+// Debug returns a string with all of this map's values
+func (bm *Beatmap) Debug() string {
+	ret := fmt.Sprintf("%s, %s", bm.Type, bm.Difficulty)
+	if len(bm.File) > 0 {
+		ret += fmt.Sprintf(" (%s)", bm.File)
+	}
+	ret += fmt.Sprintf(", rank %d, label %q, NJS %g, offset %g", bm.Rank, bm.Label, bm.NJS, bm.NJSOffset)
+	if bm.Ranked {
+		ret += fmt.Sprintf(", ranked %.2f stars %.2f PP", bm.Stars, bm.PP)
+	}
+	if bm.Stats != nil {
+		ret += fmt.Sprintf(", %d notes, %d bombs, %d walls, %.1fs, %.2f NPS (peak %.2f), %d BPM changes",
+			bm.Stats.Notes, bm.Stats.Bombs, bm.Stats.Walls, bm.Stats.Duration, bm.Stats.AvgNPS, bm.Stats.PeakNPS,
+			len(bm.Stats.BPMChanges))
+	}
+	return ret
+}
+
+// StringSet a set for strings, useful for keeping track of elements
+type StringSet map[string]struct{}
+
+// Contains returns true if `v` is in the set
+func (s StringSet) Contains(v string) bool {
+	_, ok := s[v]
+	return ok
+}
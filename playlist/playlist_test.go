@@ -0,0 +1,396 @@
+package playlist
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMakePlaylist(t *testing.T) {
+	p, err := MakePlaylist("../samples/json/playlist.bplist")
+	if err != nil {
+		t.Errorf("Playlist JSON parse failed: %v", err)
+	}
+	if len(p.Songs) != 44 {
+		t.Errorf("Expected 44 songs, got %d\n%s", len(p.Songs), p.Debug())
+	}
+}
+
+func TestPlaylistHighlights(t *testing.T) {
+	dir, err := ioutil.TempDir("", "playlist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s := Song{Hash: "abc", Name: "Song", Maps: []Beatmap{{Difficulty: ExpertPlus, Ranked: true, Type: OneSaber}}}
+	s.Highlight(s.StarsMap(DiffSelector{}))
+	p := Playlist{Title: "Highlights", Songs: []Song{s, {Hash: "def", Name: "Plain"}}}
+	path := filepath.Join(dir, "highlights.bplist")
+	if err = ioutil.WriteFile(path, p.ToJSON(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(p.ToJSON(), []byte(`"characteristic": "OneSaber"`)) {
+		t.Errorf("Expected difficulties in JSON, got\n%s", p.ToJSON())
+	}
+	read, err := MakePlaylist(path)
+	if err != nil {
+		t.Fatalf("Playlist JSON parse failed: %v", err)
+	}
+	expected := []Highlight{{Characteristic: OneSaber, Difficulty: ExpertPlus}}
+	if h := read.Songs[0].Highlights; len(h) != 1 || h[0] != expected[0] {
+		t.Errorf("Expected highlights %v, got %v", expected, h)
+	}
+	if h := read.Songs[1].Highlights; len(h) != 0 {
+		t.Errorf("Expected no highlights, got %v", h)
+	}
+}
+
+func TestIndex(t *testing.T) {
+	p, err := MakePlaylist("../samples/json/playlist.bplist")
+	if err != nil {
+		t.Fatalf("Playlist JSON parse failed: %v", err)
+	}
+	idx := p.Index()
+	if idx.Len() != len(p.Songs) {
+		t.Errorf("Expected %d indexed songs, got %d", len(p.Songs), idx.Len())
+	}
+	for _, s := range p.Songs {
+		if !idx.Contains(&Song{Hash: s.Hash}) || !idx.Contains(&Song{Key: s.Key}) {
+			t.Errorf("%s not found by hash or key", s.String())
+		}
+		if len(idx.FindByName(s.Name+" - ")) == 0 {
+			t.Errorf("%s not found by name", s.String())
+		}
+	}
+	if idx.Contains(&Song{}) {
+		t.Error("Empty song should not be found")
+	}
+}
+
+func TestMakeSong(t *testing.T) {
+	tests := []struct {
+		path    string
+		hash    string
+		mapper  string
+		maps    int
+		version string
+		bpm     float64
+		env     string
+		// first is the first map
+		first Beatmap
+	}{
+		{
+			"../samples/song-nightraid/info.dat", "9bf202f68c333421c69ca6aa15c648d65d4a1e0f", "DE125 & Skeelie", 4, "2.0.0",
+			256, "BigMirrorEnvironment",
+			Beatmap{Difficulty: Hard, File: "Hard.dat", Label: "Dusk", NJS: 21, NJSOffset: 0.5, Rank: 5, Type: Standard},
+		},
+		// This sample was made for these tests, BeatSaver has no hash for it. The reference is computed without
+		// hashFiles, with `cat Info.dat BPMInfo.dat Easy.dat Lightshow.dat ExpertPlus.dat LawlessExpert.dat
+		// LawlessLightshow.dat | sha1sum`, Lightshow.dat is shared by two difficulties and only hashed once.
+		{
+			"../samples/song-v4/Info.dat", "8de1bccec78e7d6902f5fe00c9dc5f4fca20b27e", "Mapper One, Mapper Two", 3, "4.0.1",
+			128, "WeaveEnvironment",
+			Beatmap{Difficulty: Easy, File: "Easy.dat", Lightshow: "Lightshow.dat", NJS: 10, Rank: 1, Type: Standard},
+		},
+	}
+	for _, tt := range tests {
+		s, err := MakeSong(tt.path)
+		if err != nil {
+			t.Errorf("%s: make song failed: %v", tt.path, err)
+			continue
+		}
+		if s.Hash != tt.hash {
+			t.Errorf("%s: expected hash %s, got %s", tt.path, tt.hash, s.Hash)
+		}
+		if s.Mapper != tt.mapper {
+			t.Errorf("%s: expected mapper %q, got %q", tt.path, tt.mapper, s.Mapper)
+		}
+		if len(s.Maps) != tt.maps {
+			t.Errorf("%s: expected %d maps, got %d\n%s", tt.path, tt.maps, len(s.Maps), s.Debug())
+		}
+		if s.Version != tt.version {
+			t.Errorf("%s: expected version %s, got %s", tt.path, tt.version, s.Version)
+		}
+		if s.BPM != tt.bpm || s.Environment != tt.env {
+			t.Errorf("%s: expected %g BPM in %s, got %g in %s", tt.path, tt.bpm, tt.env, s.BPM, s.Environment)
+		}
+		if len(s.Maps) > 0 && s.Maps[0] != tt.first {
+			t.Errorf("%s: expected first map %s, got %s", tt.path, tt.first.Debug(), s.Maps[0].Debug())
+		}
+	}
+}
+
+func TestSongMetadata(t *testing.T) {
+	s, err := ReadSong("../samples/song-nightraid/info.dat")
+	if err != nil {
+		t.Fatalf("Reading song failed: %v", err)
+	}
+	if s.SongFile != "song.egg" || s.Cover != "cover.jpg" {
+		t.Errorf("Expected song.egg and cover.jpg, got %s and %s", s.SongFile, s.Cover)
+	}
+	if s.PreviewStart != 29.613250732421875 || s.PreviewDuration != 10 {
+		t.Errorf("Expected preview at 29.61s for 10s, got %gs for %gs", s.PreviewStart, s.PreviewDuration)
+	}
+	expected := []Contributor{{Name: "DE125", Role: "Mapper (Expert+)"}, {Name: "Skeelie", Role: "Lighter/Mapper (Expert/Hard)"}}
+	if len(s.Contributors) != len(expected) {
+		t.Fatalf("Expected contributors %v, got %v", expected, s.Contributors)
+	}
+	for i := range expected {
+		if s.Contributors[i] != expected[i] {
+			t.Errorf("Expected contributor %v, got %v", expected[i], s.Contributors[i])
+		}
+	}
+	if s.MaxNJS() != 24 {
+		t.Errorf("Expected max NJS 24, got %g", s.MaxNJS())
+	}
+	v4, err := ReadSong("../samples/song-v4/Info.dat")
+	if err != nil {
+		t.Fatalf("Reading v4 song failed: %v", err)
+	}
+	if v4.SubName != "Extended Mix" || v4.SongFile != "song.ogg" || v4.PreviewStart != 2 {
+		t.Errorf("Wrong v4 metadata\n%s", v4.Debug())
+	}
+}
+
+func TestAnalyzeBeatmap(t *testing.T) {
+	tests := []struct {
+		path     string
+		bpm      float64
+		expected MapStats
+	}{
+		// v2 with BPM changes in custom data
+		{"../samples/song-nightraid/Hard.dat", 256, MapStats{Notes: 1343, Bombs: 32, Walls: 78, Duration: 190.986, PeakNPS: 13}},
+		// v4 with wall durations in obstaclesData, 10 beats
+		{"../samples/song-v4/ExpertPlus.dat", 128, MapStats{Notes: 4, Bombs: 1, Walls: 1, Duration: 4.6875, PeakNPS: 2}},
+	}
+	for _, tt := range tests {
+		st, err := AnalyzeBeatmap(tt.path, tt.bpm, nil)
+		if err != nil {
+			t.Errorf("%s: analysis failed: %v", tt.path, err)
+			continue
+		}
+		if st.Notes != tt.expected.Notes || st.Bombs != tt.expected.Bombs || st.Walls != tt.expected.Walls {
+			t.Errorf("%s: expected %d notes, %d bombs, %d walls, got %d, %d, %d", tt.path, tt.expected.Notes,
+				tt.expected.Bombs, tt.expected.Walls, st.Notes, st.Bombs, st.Walls)
+		}
+		if math.Abs(st.Duration-tt.expected.Duration) > 0.001 {
+			t.Errorf("%s: expected duration %.3fs, got %.3fs", tt.path, tt.expected.Duration, st.Duration)
+		}
+		if avg := float64(st.Notes) / st.Duration; math.Abs(st.AvgNPS-avg) > 0.001 {
+			t.Errorf("%s: expected %.3f NPS, got %.3f", tt.path, avg, st.AvgNPS)
+		}
+		if st.PeakNPS != tt.expected.PeakNPS {
+			t.Errorf("%s: expected peak %.2f NPS, got %.2f", tt.path, tt.expected.PeakNPS, st.PeakNPS)
+		}
+	}
+}
+
+func TestSongAnalyze(t *testing.T) {
+	s, err := MakeSong("../samples/song-v4/Info.dat")
+	if err != nil {
+		t.Fatalf("Make song failed: %v", err)
+	}
+	if err = s.Analyze(); err != nil {
+		t.Fatalf("Analysis failed: %v", err)
+	}
+	for _, bm := range s.Maps {
+		if bm.Stats == nil {
+			t.Errorf("%s not analysed", bm.Debug())
+		} else if len(bm.Stats.BPMChanges) != 0 {
+			t.Errorf("Expected no BPM changes in %s, got %v", bm.Debug(), bm.Stats.BPMChanges)
+		}
+	}
+	var copied Song
+	copied.Maps = append(copied.Maps, s.Maps...)
+	for i := range copied.Maps {
+		copied.Maps[i].Stats = nil
+	}
+	if !copied.SetStats(s.StatsByFile()) {
+		t.Error("Expected stats for all maps")
+	}
+}
+
+func TestPlaylistRoundTrip(t *testing.T) {
+	for _, path := range []string{"../samples/json/playlist.bplist", "../samples/json/playlist-custom.bplist"} {
+		file, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p, err := MakePlaylist(path)
+		if err != nil {
+			t.Fatalf("%s: parse failed: %v", path, err)
+		}
+		if out := p.ToJSON(); !bytes.Equal(out, file) {
+			t.Errorf("%s: expected unchanged playlist to be written as read, got\n%s", path, out)
+		}
+	}
+	// Changes only touch what changed
+	p, err := MakePlaylist("../samples/json/playlist-custom.bplist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Songs[0].Hash != "9bf202f68c333421c69ca6aa15c648d65d4a1e0f" || p.Songs[1].Key != "1234" || len(p.Songs[0].Highlights) != 1 {
+		t.Fatalf("Unexpected songs\n%s", p.Debug())
+	}
+	p.Songs = p.Songs[:2]
+	p.Songs[1].Highlight(&Beatmap{Difficulty: Hard, Type: Standard})
+	p.Title = "Renamed"
+	var out interface{}
+	if err = json.Unmarshal(p.ToJSON(), &out); err != nil {
+		t.Fatalf("Invalid JSON written: %v", err)
+	}
+	var expected interface{}
+	_ = json.Unmarshal([]byte(`{
+		"playlistTitle": "Renamed",
+		"playlistAuthor": "Mapper & Co",
+		"playlistDescription": "Caf\u00e9 songs",
+		"customData": {
+			"syncURL": "https://example.com/sync.bplist",
+			"archiveUrl": "https://example.com/pack.zip",
+			"AllowDuplicates": false
+		},
+		"songs": [
+			{
+				"key": "1A2B",
+				"hash": "9BF202F68C333421C69CA6AA15C648D65D4A1E0F",
+				"levelid": "custom_level_9BF202F68C333421C69CA6AA15C648D65D4A1E0F",
+				"songName": "Night Raid",
+				"dateAdded": "2020-05-01T12:00:00Z",
+				"difficulties": [{"characteristic": "Standard", "name": "expertPlus"}]
+			},
+			{
+				"key": 1234,
+				"hash": "8de1bccec78e7d6902f5fe00c9dc5f4fca20b27e",
+				"songName": "V4",
+				"customData": {},
+				"difficulties": [{"characteristic": "Standard", "name": "Hard"}]
+			}
+		],
+		"playlistSongCount": 2
+	}`), &expected)
+	if !reflect.DeepEqual(out, expected) {
+		t.Errorf("Unexpected playlist written\n%s", p.ToJSON())
+	}
+	if !bytes.Contains(p.ToJSON(), []byte("\r\n  \"playlistAuthor\": \"Mapper & Co\",\r\n")) {
+		t.Errorf("Expected formatting to be kept\n%s", p.ToJSON())
+	}
+}
+
+func TestPlaylistRoundTripHandFormatted(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"../samples/json/playlist-hand.bplist", "{\n" +
+			"\t\"playlistTitle\" : \"Renamed\",\n" +
+			"\t\"customData\" : { \"syncURL\":\"https://example.com/hand.bplist\",  \"note\" : [1,2,  3] },\n" +
+			"\t\"songs\" : [\n" +
+			"\t\t{ \"hash\": \"9bf202f68c333421c69ca6aa15c648d65d4a1e0f\", \"songName\": \"Night Raid\", " +
+			"\"difficulties\": [{\"characteristic\":\"Standard\",\"name\":\"Hard\"}] },\n" +
+			"\t\t{\n" +
+			"\t\t\t\"hash\":\"ABCDEF0123456789ABCDEF0123456789ABCDEF01\",\n" +
+			"\t\t\t\"songName\":\"Spaced\",   \"customData\":{\"x\":true}\n" +
+			"\t\t},\n" +
+			"\t\t{\n" +
+			"\t\t\t\"hash\": \"0123\",\n" +
+			"\t\t\t\"songName\": \"New\"\n" +
+			"\t\t}\n" +
+			"\t]\n" +
+			"}"},
+		{"../samples/json/playlist-compact.bplist", `{"playlistTitle":"Renamed","playlistAuthor":"Me","songs":[` +
+			`{"hash":"9bf202f68c333421c69ca6aa15c648d65d4a1e0f","songName":"Night Raid",` +
+			`"difficulties":[{"characteristic":"Standard","name":"Hard"}]},{"hash":"0123","songName":"New"}]}` + "\n"},
+	}
+	for _, tt := range tests {
+		file, err := ioutil.ReadFile(tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p, err := MakePlaylist(tt.path)
+		if err != nil {
+			t.Fatalf("%s: parse failed: %v", tt.path, err)
+		}
+		if out := p.ToJSON(); !bytes.Equal(out, file) {
+			t.Errorf("%s: expected unchanged playlist to be written as read, got\n%s", tt.path, out)
+		}
+		// Only the title, the removed song, the highlight and the added song change, the rest is written as read
+		p.Title = "Renamed"
+		p.Songs = append([]Song{p.Songs[0]}, p.Songs[2:]...)
+		p.Songs[0].Highlight(&Beatmap{Difficulty: Hard, Type: Standard})
+		p.Songs = append(p.Songs, Song{Hash: "0123", Name: "New"})
+		if out := string(p.ToJSON()); out != tt.expected {
+			t.Errorf("%s: expected\n%s\ngot\n%s", tt.path, tt.expected, out)
+		}
+	}
+}
+
+func TestLevelIDs(t *testing.T) {
+	path := "../samples/json/playlist-levels.bplist"
+	p, err := MakePlaylist(path)
+	if err != nil {
+		t.Fatalf("Playlist JSON parse failed: %v", err)
+	}
+	if len(p.Songs) != 3 {
+		t.Fatalf("Expected 3 songs, got %d", len(p.Songs))
+	}
+	for _, s := range p.Songs[:2] {
+		if !s.IsBuiltIn() || s.Hash != "" {
+			t.Errorf("Expected %s to be an official level", s.String())
+		}
+	}
+	custom := p.Songs[2]
+	if custom.IsBuiltIn() || custom.Hash != "9bf202f68c333421c69ca6aa15c648d65d4a1e0f" {
+		t.Errorf("Expected custom level with hash from its level ID, got\n%s", custom.Debug())
+	}
+	idx := p.Index()
+	if found, ok := idx.Find(&Song{LevelID: "100bills"}); !ok || found.Name != "$100 Bills" {
+		t.Errorf("Expected official level to be found by level ID, got %v", found.Name)
+	}
+	if p.Songs[0].Equals(&p.Songs[1]) {
+		t.Error("Different official levels must not be equal")
+	}
+	if h := LevelHash("custom_level_ABC WIP"); h != "abc" {
+		t.Errorf("Expected hash abc of WIP level, got %s", h)
+	}
+	file, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out := p.ToJSON(); !bytes.Equal(out, file) {
+		t.Errorf("Expected unchanged playlist to be written as read, got\n%s", out)
+	}
+}
+
+func TestPlaylistInstalled(t *testing.T) {
+	path := "../samples/json/playlist-custom.bplist"
+	p, err := MakePlaylist(path)
+	if err != nil {
+		t.Fatalf("Playlist JSON parse failed: %v", err)
+	}
+	s, err := MakeSong("../samples/song-nightraid/info.dat")
+	if err != nil {
+		t.Fatalf("Make song failed: %v", err)
+	}
+	s.Path = "../samples/song-nightraid"
+	p.Installed(NewIndex([]Song{s}))
+	got := p.Songs[0]
+	if got.Path != s.Path || got.BPM != 256 || got.Environment != "BigMirrorEnvironment" || got.MaxNJS() != 24 {
+		t.Errorf("Expected info.dat metadata in playlist entry, got\n%s", got.Debug())
+	}
+	if !strings.Contains(p.String(), "(256 BPM, BigMirrorEnvironment)") {
+		t.Errorf("Expected BPM and environment in listing, got\n%s", p.String())
+	}
+	file, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out := p.ToJSON(); !bytes.Equal(out, file) {
+		t.Errorf("Expected installed playlist to be written as read, got\n%s", out)
+	}
+}
@@ -0,0 +1,447 @@
+package playlist
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// defaultIndent is the indent of playlists we create
+const defaultIndent = " "
+
+// rawField is a member of a JSON object, its value kept as written
+type rawField struct {
+	Key   string
+	Value json.RawMessage
+	// lead is the whitespace and comma before the member, head its key and colon with the space around them, as read
+	lead string
+	head string
+	// node is the value it was changed to, set once Value no longer holds the bytes read
+	node interface{}
+}
+
+// rawObject is a JSON object which keeps the order, values and formatting of its members as written
+//
+// It lets playlists keep fields we do not understand when they are written back, and only reformat what changed
+type rawObject struct {
+	fields []rawField
+	// src is the object as read and tail the whitespace before its closing brace, both empty for new objects
+	src  json.RawMessage
+	tail string
+	// changed is set once a member was changed, added or removed
+	changed bool
+}
+
+// rawArray is a JSON array of objects, written with the formatting of the array it replaces
+type rawArray struct {
+	elems []rawObject
+	// leads are the whitespace and comma before each element of the array read, tail the whitespace before its
+	// closing bracket
+	leads []string
+	tail  string
+}
+
+// jsonFormat is how a playlist file was formatted, it is written back the same way
+type jsonFormat struct {
+	bom bool
+	// indent is empty for compact files
+	indent   string
+	newline  string
+	trailing bool
+}
+
+// defaultFormat is the format of playlists we create
+var defaultFormat = jsonFormat{indent: defaultIndent, newline: "\n", trailing: true}
+
+// parseRawObject returns the members of the JSON object in `data`
+func parseRawObject(data []byte) (o rawObject, err error) {
+	data = bytes.TrimSpace(data)
+	if !json.Valid(data) {
+		return o, fmt.Errorf("invalid JSON")
+	}
+	if len(data) == 0 || data[0] != '{' {
+		return o, fmt.Errorf("expected a JSON object")
+	}
+	o.src = data
+	// Members start after the opening brace or the previous value
+	start := 1
+	for {
+		i := skipSpace(data, start)
+		if data[i] == '}' {
+			o.tail = string(data[start:i])
+			return
+		}
+		if data[i] == ',' {
+			i = skipSpace(data, i+1)
+		}
+		var f rawField
+		f.lead = string(data[start:i])
+		keyEnd := scanString(data, i)
+		if err = json.Unmarshal(data[i:keyEnd], &f.Key); err != nil {
+			return
+		}
+		valueStart := skipSpace(data, skipSpace(data, keyEnd)+1)
+		f.head = string(data[i:valueStart])
+		start = scanValue(data, valueStart)
+		f.Value = data[valueStart:start]
+		o.fields = append(o.fields, f)
+	}
+}
+
+// newRawArray returns `elems` to be written like the array `old`, which may be empty
+func newRawArray(elems []rawObject, old json.RawMessage) rawArray {
+	arr := rawArray{elems: elems}
+	old = bytes.TrimSpace(old)
+	if len(old) == 0 || old[0] != '[' {
+		return arr
+	}
+	start := 1
+	for {
+		i := skipSpace(old, start)
+		if i >= len(old) || old[i] == ']' {
+			arr.tail = string(old[start:i])
+			return arr
+		}
+		if old[i] == ',' {
+			i = skipSpace(old, i+1)
+		}
+		arr.leads = append(arr.leads, string(old[start:i]))
+		start = scanValue(old, i)
+	}
+}
+
+// skipSpace returns the index of the first byte from `i` which is not JSON whitespace
+func skipSpace(data []byte, i int) int {
+	for i < len(data) && strings.IndexByte(" \t\r\n", data[i]) >= 0 {
+		i++
+	}
+	return i
+}
+
+// scanString returns the end of the JSON string starting at data[i]
+func scanString(data []byte, i int) int {
+	for i++; i < len(data); i++ {
+		switch data[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return i
+}
+
+// scanValue returns the end of the valid JSON value starting at data[i]
+func scanValue(data []byte, i int) int {
+	var depth int
+	for ; i < len(data); i++ {
+		switch data[i] {
+		case '"':
+			i = scanString(data, i) - 1
+			if depth == 0 {
+				return i + 1
+			}
+		case '{', '[':
+			depth++
+		case '}', ']':
+			if depth == 0 {
+				return i
+			}
+			if depth--; depth == 0 {
+				return i + 1
+			}
+		case ',', ' ', '\t', '\r', '\n':
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return i
+}
+
+// detectFormat returns the format of the JSON file `data`
+func detectFormat(data []byte) (f jsonFormat) {
+	bom := []byte("\xef\xbb\xbf")
+	f.bom = bytes.HasPrefix(data, bom)
+	data = bytes.TrimPrefix(data, bom)
+	f.newline = "\n"
+	if bytes.Contains(data, []byte("\r\n")) {
+		f.newline = "\r\n"
+	}
+	body := bytes.TrimRight(data, " \t\r\n")
+	f.trailing = len(body) < len(data)
+	if i := bytes.IndexByte(body, '\n'); i >= 0 {
+		rest := body[i+1:]
+		f.indent = string(rest[:len(rest)-len(bytes.TrimLeft(rest, " \t"))])
+		if f.indent == "" {
+			f.indent = defaultIndent
+		}
+	}
+	return
+}
+
+// get returns the value of `key`
+func (o rawObject) get(key string) (json.RawMessage, bool) {
+	for _, f := range o.fields {
+		if f.Key == key {
+			return f.Value, true
+		}
+	}
+	return nil, false
+}
+
+// put sets `key` to `value`, the JSON encoding of `node`, new keys are added at the end
+func (o *rawObject) put(key string, value json.RawMessage, node interface{}) {
+	o.changed = true
+	for i := range o.fields {
+		if o.fields[i].Key == key {
+			o.fields[i].Value = value
+			o.fields[i].node = node
+			return
+		}
+	}
+	o.fields = append(o.fields, rawField{Key: key, Value: value, node: node})
+}
+
+// remove deletes `key`
+func (o *rawObject) remove(key string) {
+	for i := range o.fields {
+		if o.fields[i].Key == key {
+			o.fields = append(o.fields[:i:i], o.fields[i+1:]...)
+			o.changed = true
+			return
+		}
+	}
+}
+
+// set sets `key` to `v`, unless its current value means the same according to `same`
+//
+// With `omitEmpty`, a zero `v` removes the key instead. Objects read from a file only get missing keys which are
+// not zero. `same` defaults to comparing the decoded JSON values.
+func (o *rawObject) set(key string, v interface{}, omitEmpty bool, same func(json.RawMessage) bool) error {
+	encoded, err := encodeJSON(v)
+	if err != nil {
+		return err
+	}
+	zero := reflect.ValueOf(v).IsZero()
+	if old, ok := o.get(key); ok {
+		if same == nil {
+			same = func(old json.RawMessage) bool { return sameJSON(old, encoded) }
+		}
+		if same(old) {
+			return nil
+		}
+	} else if zero && o.src != nil {
+		return nil
+	}
+	if omitEmpty && zero {
+		o.remove(key)
+		return nil
+	}
+	o.put(key, encoded, v)
+	return nil
+}
+
+// clone returns a copy which can be changed without changing `o`
+func (o rawObject) clone() rawObject {
+	o.fields = append([]rawField(nil), o.fields...)
+	return o
+}
+
+// MarshalJSON writes the elements without their formatting
+func (arr rawArray) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i, o := range arr.elems {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		data, err := o.MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(data)
+	}
+	buf.WriteByte(']')
+	return buf.Bytes(), nil
+}
+
+// MarshalJSON writes the members in order, values as they were read, without their formatting
+func (o rawObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range o.fields {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := encodeJSON(f.Key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		if err = json.Compact(&buf, f.Value); err != nil {
+			return nil, err
+		}
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// write returns the file contents of `o` formatted as `f`
+//
+// Members and array elements which did not change are written as they were read, only changed values are formatted
+func (f jsonFormat) write(o rawObject) ([]byte, error) {
+	var buf bytes.Buffer
+	if f.bom {
+		buf.WriteString("\xef\xbb\xbf")
+	}
+	if err := f.writeObject(&buf, o, ""); err != nil {
+		return nil, err
+	}
+	if f.trailing {
+		buf.WriteString(f.newline)
+	}
+	return buf.Bytes(), nil
+}
+
+// writeObject writes `o`, which starts on a line indented by `indent`
+func (f jsonFormat) writeObject(buf *bytes.Buffer, o rawObject, indent string) error {
+	if !o.changed && o.src != nil {
+		buf.Write(o.src)
+		return nil
+	}
+	// New members are written like the last member read, or on their own line if there is none
+	lead, sep := f.newline+indent+f.indent, ": "
+	if f.indent == "" {
+		lead, sep = "", ":"
+	}
+	var read bool
+	for _, field := range o.fields {
+		if field.head != "" {
+			lead = strings.TrimLeft(field.lead, ",")
+			sep = field.head[strings.LastIndexByte(field.head, '"')+1:]
+			read = true
+		}
+	}
+	buf.WriteByte('{')
+	for i, field := range o.fields {
+		fieldLead, head := field.lead, field.head
+		if head == "" {
+			key, err := encodeJSON(field.Key)
+			if err != nil {
+				return err
+			}
+			fieldLead, head = lead, string(key)+sep
+		}
+		if i == 0 {
+			fieldLead = strings.Replace(fieldLead, ",", "", 1)
+		} else if !strings.Contains(fieldLead, ",") {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(fieldLead)
+		buf.WriteString(head)
+		if err := f.writeValue(buf, field, fieldLead, lineIndent(fieldLead, indent+f.indent)); err != nil {
+			return err
+		}
+	}
+	tail := o.tail
+	if !read && len(o.fields) > 0 && f.indent != "" {
+		tail = f.newline + indent
+	}
+	buf.WriteString(tail)
+	buf.WriteByte('}')
+	return nil
+}
+
+// writeValue writes the value of `field`, which starts on a line indented by `indent` after `lead`
+func (f jsonFormat) writeValue(buf *bytes.Buffer, field rawField, lead string, indent string) error {
+	switch node := field.node.(type) {
+	case nil:
+		buf.Write(field.Value)
+		return nil
+	case rawObject:
+		return f.writeObject(buf, node, indent)
+	case rawArray:
+		return f.writeArray(buf, node, indent)
+	}
+	// Members sharing a line with others, and all of compact files, stay on one line
+	if f.indent == "" || !strings.Contains(lead, "\n") {
+		buf.Write(field.Value)
+		return nil
+	}
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, field.Value, indent, f.indent); err != nil {
+		return err
+	}
+	// JSON strings cannot hold raw newlines, all of them are ours
+	buf.WriteString(strings.ReplaceAll(indented.String(), "\n", f.newline))
+	return nil
+}
+
+// writeArray writes `arr`, which starts on a line indented by `indent`
+//
+// Elements are put on lines like the ones of the array read, elements added after them like its last one
+func (f jsonFormat) writeArray(buf *bytes.Buffer, arr rawArray, indent string) error {
+	if len(arr.elems) == 0 {
+		buf.WriteString("[]")
+		return nil
+	}
+	lead, tail := f.newline+indent+f.indent, f.newline+indent
+	if f.indent == "" {
+		lead, tail = "", ""
+	}
+	if len(arr.leads) > 0 {
+		tail = arr.tail
+	}
+	buf.WriteByte('[')
+	for i, o := range arr.elems {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		elemLead := lead
+		if n := len(arr.leads); n > i {
+			elemLead = strings.TrimLeft(arr.leads[i], ",")
+		} else if n > 0 {
+			elemLead = strings.TrimLeft(arr.leads[n-1], ",")
+		}
+		buf.WriteString(elemLead)
+		if err := f.writeObject(buf, o, lineIndent(elemLead, indent+f.indent)); err != nil {
+			return err
+		}
+	}
+	buf.WriteString(tail)
+	buf.WriteByte(']')
+	return nil
+}
+
+// lineIndent returns the indent of the line `lead` ends on, `def` if it does not start a new line
+func lineIndent(lead string, def string) string {
+	i := strings.LastIndexByte(lead, '\n')
+	if i < 0 {
+		return def
+	}
+	return lead[i+1:]
+}
+
+// encodeJSON returns `v` as compact JSON without escaping HTML characters, like our playlists always were
+func encodeJSON(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// sameJSON returns true if `a` and `b` decode to the same value
+func sameJSON(a []byte, b []byte) bool {
+	var va, vb interface{}
+	if json.Unmarshal(a, &va) != nil || json.Unmarshal(b, &vb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
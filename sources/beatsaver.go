@@ -1,8 +1,12 @@
-package main
+package sources
 
 import (
 	"encoding/json"
+	"sort"
 	"strings"
+
+	"github.com/cosandr/go-beat-playlist/playlist"
+	log "github.com/sirupsen/logrus"
 )
 
 // BeatSaverSong is a BeatSaver song
@@ -28,15 +32,15 @@ type BeatSaverMetaChar struct {
 }
 
 // MakeBeatSaverPlaylist returns a Playlist from a byte array (API response data)
-func MakeBeatSaverPlaylist(file *[]byte) (p Playlist, err error) {
+func MakeBeatSaverPlaylist(file *[]byte) (p playlist.Playlist, err error) {
 	var resp []BeatSaverSong
 	err = json.Unmarshal(*file, &resp)
 	if err != nil {
 		return
 	}
-	var songs []Song
+	var songs []playlist.Song
 	for _, r := range resp {
-		s := Song{
+		s := playlist.Song{
 			Name:   r.Metadata.Name,
 			Author: r.Metadata.Author,
 			Key:    strings.ToLower(r.Key),
@@ -44,18 +48,10 @@ func MakeBeatSaverPlaylist(file *[]byte) (p Playlist, err error) {
 			Mapper: r.Metadata.Mapper,
 			URL:    r.URL,
 		}
-		maps := []Beatmap{}
-		for _, diff := range r.Metadata.Chars {
-			for k, v := range diff.Diffs {
-				if v != nil {
-					maps = append(maps, Beatmap{Type: diff.Name, Difficulty: k})
-				}
-			}
-		}
-		s.Maps = maps
+		s.Maps = r.Metadata.beatmaps()
 		songs = append(songs, s)
 	}
-	p = Playlist{
+	p = playlist.Playlist{
 		Title: "BeatSaver Response",
 		Songs: songs,
 	}
@@ -63,28 +59,46 @@ func MakeBeatSaverPlaylist(file *[]byte) (p Playlist, err error) {
 }
 
 // MakeBeatSaverSong returns a Song from a byte array (API response data)
-func MakeBeatSaverSong(file *[]byte) (s Song, err error) {
+func MakeBeatSaverSong(file *[]byte) (s playlist.Song, err error) {
 	var resp BeatSaverSong
 	err = json.Unmarshal(*file, &resp)
 	if err != nil {
 		return
 	}
-	maps := []Beatmap{}
-	for _, diff := range resp.Metadata.Chars {
-		for k, v := range diff.Diffs {
-			if v != nil {
-				maps = append(maps, Beatmap{Type: diff.Name, Difficulty: k})
-			}
-		}
-	}
-	s = Song{
+	s = playlist.Song{
 		Name:   resp.Metadata.Name,
 		Author: resp.Metadata.Author,
 		Key:    strings.ToLower(resp.Key),
 		Hash:   strings.ToLower(resp.Hash),
 		Mapper: resp.Metadata.Mapper,
 		URL:    resp.URL,
-		Maps:   maps,
+		Maps:   resp.Metadata.beatmaps(),
 	}
 	return
 }
+
+// beatmaps returns the maps of all characteristics, from Easy to Expert+ within each characteristic
+//
+// Difficulties without data are not in the song
+func (m *BeatSaverMeta) beatmaps() []playlist.Beatmap {
+	maps := []playlist.Beatmap{}
+	for _, char := range m.Chars {
+		var diffs []playlist.Beatmap
+		for k, v := range char.Diffs {
+			if v == nil {
+				continue
+			}
+			d, err := playlist.ParseDifficulty(k)
+			if err != nil {
+				log.Debugf("Skipping BeatSaver map: %v", err)
+				continue
+			}
+			diffs = append(diffs, playlist.Beatmap{Type: playlist.ParseCharacteristic(char.Name), Difficulty: d, Rank: d.Rank()})
+		}
+		sort.Slice(diffs, func(i, j int) bool {
+			return diffs[i].Difficulty < diffs[j].Difficulty
+		})
+		maps = append(maps, diffs...)
+	}
+	return maps
+}
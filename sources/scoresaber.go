@@ -0,0 +1,75 @@
+package sources
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/cosandr/go-beat-playlist/playlist"
+	log "github.com/sirupsen/logrus"
+)
+
+// ScoreSaberResp represents a list of songs in ScoreSaber's API response
+type ScoreSaberResp struct {
+	Songs []ScoreSaberSong `json:"songs"`
+}
+
+// ScoreSaberSong represents a song in ScoreSaber's API response
+type ScoreSaberSong struct {
+	UID    int     `json:"uid"`
+	ID     string  `json:"id"`
+	Name   string  `json:"name"`
+	Author string  `json:"songAuthorName"`
+	Mapper string  `json:"levelAuthorName"`
+	Stars  float64 `json:"stars"`
+	// Diff is the leaderboard's difficulty, such as _ExpertPlus_SoloStandard
+	Diff   string `json:"diff"`
+	Ranked int    `json:"ranked"`
+}
+
+// ToInternal returns a Song from this API response, with the leaderboard's map if its difficulty is known
+func (s *ScoreSaberSong) ToInternal() playlist.Song {
+	ret := playlist.Song{
+		Hash:   strings.ToLower(s.ID),
+		Name:   s.Name,
+		Author: s.Author,
+		Mapper: s.Mapper,
+		Stars:  s.Stars,
+	}
+	c, d, err := playlist.ParseScoreSaberDifficulty(s.Diff)
+	if err != nil {
+		log.Debugf("Unknown ScoreSaber difficulty of %s: %v", s.Name, err)
+		return ret
+	}
+	ret.Maps = []playlist.Beatmap{{Type: c, Difficulty: d, Rank: d.Rank(), Ranked: s.Ranked == 1, Stars: s.Stars}}
+	return ret
+}
+
+// MakeScoreSaberPlaylist returns a Playlist from a byte array (API response data)
+func MakeScoreSaberPlaylist(file *[]byte) (p playlist.Playlist, err error) {
+	var resp ScoreSaberResp
+	err = json.Unmarshal(*file, &resp)
+	if err != nil {
+		return
+	}
+	var songs []playlist.Song
+	// Each leaderboard is one difficulty, they are added to the song's first entry
+	index := make(map[string]int)
+	for _, s := range resp.Songs {
+		song := s.ToInternal()
+		i, ok := index[song.Hash]
+		if !ok {
+			index[song.Hash] = len(songs)
+			songs = append(songs, song)
+			continue
+		}
+		songs[i].Maps = append(songs[i].Maps, song.Maps...)
+		if song.Stars > songs[i].Stars {
+			songs[i].Stars = song.Stars
+		}
+	}
+	p = playlist.Playlist{
+		Title: "ScoreSaber Response",
+		Songs: songs,
+	}
+	return
+}
@@ -1,9 +1,12 @@
-package main
+package sources
 
 import (
 	"encoding/json"
 	"strconv"
 	"strings"
+
+	"github.com/cosandr/go-beat-playlist/playlist"
+	log "github.com/sirupsen/logrus"
 )
 
 // SongBrowserSong represents a song in Beat Saber Song Browser's API response
@@ -23,32 +26,46 @@ type SongBrowserDiff struct {
 }
 
 // MakeSongBrowserPlaylist returns a Playlist from a byte array (API response data)
-func MakeSongBrowserPlaylist(file *[]byte) (p Playlist, err error) {
+func MakeSongBrowserPlaylist(file *[]byte) (p playlist.Playlist, err error) {
 	var resp map[string]SongBrowserSong
 	err = json.Unmarshal(*file, &resp)
 	if err != nil {
 		return
 	}
-	var songs []Song
+	var songs []playlist.Song
 	for k, v := range resp {
-		pp, _ := strconv.ParseFloat(v.Diffs[0].PP, 64)
-		stars, _ := strconv.ParseFloat(v.Diffs[0].Star, 64)
-		s := Song{
+		s := playlist.Song{
 			Name:   v.Name,
 			Key:    strings.ToLower(v.Key),
 			Hash:   strings.ToLower(k),
 			Mapper: v.Mapper,
-			PP:     pp,
-			Stars:  stars,
 		}
-		maps := []Beatmap{}
+		maps := []playlist.Beatmap{}
 		for _, diff := range v.Diffs {
-			maps = append(maps, Beatmap{Type: "Standard", Difficulty: diff.Diff})
+			d, err := playlist.ParseDifficulty(diff.Diff)
+			if err != nil {
+				log.Debugf("Skipping Song Browser map of %s: %v", v.Name, err)
+				continue
+			}
+			pp, _ := strconv.ParseFloat(diff.PP, 64)
+			stars, _ := strconv.ParseFloat(diff.Star, 64)
+			maps = append(maps, playlist.Beatmap{
+				Type:       playlist.Standard,
+				Difficulty: d,
+				PP:         pp,
+				Rank:       d.Rank(),
+				// Unranked maps have stars but no PP
+				Ranked: pp > 0,
+				Stars:  stars,
+			})
 		}
 		s.Maps = maps
+		// The song's own values are those of its hardest ranked map
+		s.PP = s.PPFor(playlist.DiffSelector{})
+		s.Stars = s.StarsFor(playlist.DiffSelector{})
 		songs = append(songs, s)
 	}
-	p = Playlist{
+	p = playlist.Playlist{
 		Title: "SongBrowser Response",
 		Songs: songs,
 	}
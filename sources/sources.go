@@ -0,0 +1,119 @@
+// Package sources holds clients for the BeatSaver, ScoreSaber and Song Browser APIs
+package sources
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cosandr/go-beat-playlist/internal/web"
+	"github.com/cosandr/go-beat-playlist/playlist"
+)
+
+const (
+	// ScoreSaberStarsURL Scoresaber API URL for getting top X stars
+	scoreSaberStarsURL = "https://scoresaber.com/api.php?function=get-leaderboards&cat=3&limit=%[1]d&page=1&ranked=1"
+	// BeatStarAll Dump of all maps
+	beatStarAll = "https://cdn.wes.cloud/beatstar/bssb/v2-all.json"
+	// BeatStarRanked Dump of all ranked maps, in desceding PP order
+	beatStarRanked = "https://cdn.wes.cloud/beatstar/bssb/v2-ranked.json"
+	// BeatSaverDump Dump of Beatsaver database
+	beatSaverDump = "https://beatsaver.com/api/download/dump/maps"
+	// beatSaverByKey URL to download from key
+	beatSaverByKey = "https://beatsaver.com/api/maps/detail/%s"
+	// beatSaverByHash URL to download from hash
+	beatSaverByHash = "https://beatsaver.com/api/maps/by-hash/%s"
+)
+
+// DownloadSongInfo fetches song info from BeatSaver API, returns a new Song
+func DownloadSongInfo(s *playlist.Song) (dlSong playlist.Song, err error) {
+	return DownloadSongInfoContext(context.Background(), s)
+}
+
+// DownloadSongInfoContext is DownloadSongInfo, cancelled when `ctx` is done
+func DownloadSongInfoContext(ctx context.Context, s *playlist.Song) (dlSong playlist.Song, err error) {
+	var url string
+	if len(s.Hash) > 0 {
+		url = fmt.Sprintf(beatSaverByHash, s.Hash)
+	} else if len(s.Key) > 0 {
+		url = fmt.Sprintf(beatSaverByKey, s.Key)
+	} else {
+		err = fmt.Errorf("%s has no key or hash", s.Name)
+		return
+	}
+	outSong, err := web.Fetch(ctx, url)
+	if err != nil {
+		return
+	}
+	dlSong, err = MakeBeatSaverSong(&outSong)
+	if err != nil {
+		return
+	}
+	return
+}
+
+// DownloadStarsPlaylist returns a Playlist of top `num` songs sorted by the star difficulty `sel` picks
+//
+// With `each`, songs are listed once per ranked difficulty
+func DownloadStarsPlaylist(num int, sel playlist.DiffSelector, each bool) (p playlist.Playlist, err error) {
+	body, err := web.Fetch(context.Background(), fmt.Sprintf(scoreSaberStarsURL, num))
+	if err != nil {
+		return
+	}
+	p, err = MakeScoreSaberPlaylist(&body)
+	if err != nil {
+		return
+	}
+	if len(p.Songs) == 0 {
+		err = fmt.Errorf("response parsing failed")
+		return
+	}
+	if each {
+		p = p.SplitRanked()
+	}
+	p.SortByStars(sel)
+	if num < len(p.Songs) {
+		p.Songs = p.Songs[:num]
+	}
+	for i := range p.Songs {
+		p.Songs[i].Highlight(p.Songs[i].StarsMap(sel))
+	}
+	return
+}
+
+// DownloadScrapedData downloads scraped data for all or ranked songs
+func DownloadScrapedData(ranked bool) (p playlist.Playlist, err error) {
+	var url string
+	if ranked {
+		url = beatStarRanked
+	} else {
+		url = beatStarAll
+	}
+	body, err := web.Fetch(context.Background(), url)
+	if err != nil {
+		return
+	}
+	p, err = MakeSongBrowserPlaylist(&body)
+	return
+}
+
+// DownloadPPPlaylist returns a Playlist of top `num` songs sorted by the PP `sel` picks
+//
+// With `each`, songs are listed once per ranked difficulty
+func DownloadPPPlaylist(num int, sel playlist.DiffSelector, each bool) (p playlist.Playlist, err error) {
+	p, err = DownloadScrapedData(true)
+	if err != nil {
+		return
+	}
+	if each {
+		p = p.SplitRanked()
+	}
+	p.SortByPP(sel)
+	// Only keep num songs
+	if num < len(p.Songs) {
+		p.Songs = p.Songs[:num]
+	}
+	for i := range p.Songs {
+		p.Songs[i].Highlight(p.Songs[i].PPMap(sel))
+	}
+	return
+}
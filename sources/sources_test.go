@@ -0,0 +1,81 @@
+package sources
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/cosandr/go-beat-playlist/playlist"
+)
+
+func TestDownloadSongInfo(t *testing.T) {
+	s := playlist.Song{Hash: "9bf202f68c333421c69ca6aa15c648d65d4a1e0f", Name: "Night Raid"}
+	out, err := DownloadSongInfo(&s)
+	if err != nil {
+		t.Errorf("Song info download failed: %v", err)
+	} else {
+		t.Logf("Song info download successful\n%s", out.Debug())
+	}
+}
+
+func TestSourceDifficulties(t *testing.T) {
+	file, err := ioutil.ReadFile("../samples/json/songbrowser-ranked.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := MakeSongBrowserPlaylist(&file)
+	if err != nil {
+		t.Fatalf("Song Browser parsing failed: %v", err)
+	}
+	var found bool
+	for _, s := range p.Songs {
+		if s.Hash == "92c7490d903f3e676069b92b7de9e56b03a9677a" {
+			found = s.FindMap(playlist.Standard, playlist.ExpertPlus) != nil
+		}
+	}
+	if !found {
+		t.Error("Expected Song Browser Expert+ to be Standard ExpertPlus")
+	}
+	for _, s := range p.Songs {
+		if s.Key != "78a8" {
+			continue
+		}
+		bm := s.FindMap(playlist.Standard, playlist.Expert)
+		if bm == nil || !bm.Ranked || bm.PP != 336.057 || bm.Stars != 7.9 {
+			t.Errorf("Expected ranked Expert with 336.057 PP and 7.9 stars, got %v", bm)
+		}
+		if s.PP != 476.433 || s.Stars != 11.24 {
+			t.Errorf("Expected song values of Expert+, got %g PP and %g stars", s.PP, s.Stars)
+		}
+	}
+	file, err = ioutil.ReadFile("../samples/json/scoresaber-api.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err = MakeScoreSaberPlaylist(&file)
+	if err != nil {
+		t.Fatalf("ScoreSaber parsing failed: %v", err)
+	}
+	if len(p.Songs) == 0 || p.Songs[0].FindMap(playlist.Standard, playlist.ExpertPlus) == nil {
+		t.Error("Expected ScoreSaber _ExpertPlus_SoloStandard to be Standard ExpertPlus")
+	}
+	file = []byte(`{"key": "1a2b", "hash": "ABC", "metadata": {"characteristics": [
+		{"name": "Standard", "difficulties": {"expertPlus": {}, "easy": {}, "hard": null}},
+		{"name": "OneSaber", "difficulties": {"expert": {}}}]}}`)
+	s, err := MakeBeatSaverSong(&file)
+	if err != nil {
+		t.Fatalf("BeatSaver parsing failed: %v", err)
+	}
+	expected := []playlist.Beatmap{
+		{Difficulty: playlist.Easy, Rank: 1, Type: playlist.Standard},
+		{Difficulty: playlist.ExpertPlus, Rank: 9, Type: playlist.Standard},
+		{Difficulty: playlist.Expert, Rank: 7, Type: playlist.OneSaber},
+	}
+	if len(s.Maps) != len(expected) {
+		t.Fatalf("Expected %d BeatSaver maps, got %d", len(expected), len(s.Maps))
+	}
+	for i := range expected {
+		if s.Maps[i] != expected[i] {
+			t.Errorf("Expected BeatSaver map %s, got %s", expected[i].Debug(), s.Maps[i].Debug())
+		}
+	}
+}